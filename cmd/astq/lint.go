@@ -0,0 +1,90 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/token"
+	"os"
+
+	"github.com/flier/astq/pkg/lint"
+	"github.com/flier/astq/pkg/report"
+	"github.com/flier/astq/pkg/selector"
+)
+
+// runLint checks the GO sources with the rules, and exits with status 1 when any rule reports, or 2 on errors.
+func runLint(args []string) {
+	flags := flag.NewFlagSet(generator.Name+" lint", flag.ExitOnError)
+
+	rulesFile := flags.String("rules", ".astq.json", "read the rules from file")
+
+	flags.Var(&libraries, "lib", "load the named queries from the library file, could be repeated")
+	flags.StringVar(&format, "format", "text", "output format: text, json, jsonl or sarif")
+
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s lint [flags] [package|file|directory ...]\n\n", generator.Name)
+
+		flags.PrintDefaults()
+
+		fmt.Fprintf(flags.Output(), "\nThe exit status is %d if any rule reported, or %d on errors.\n", exitMatched, exitError)
+	}
+
+	flags.Parse(args)
+
+	lib := loadLibraries()
+
+	rules, err := lint.LoadRules(*rulesFile, lib)
+	if err != nil {
+		fatalf("fail to load rules, %v", err)
+	}
+
+	for _, rule := range rules {
+		for _, diag := range selector.Validate(rule.Compiled()) {
+			fmt.Fprintf(os.Stderr, "warning: rule %s: %s: %s\n", rule.ID, diag.Node, diag)
+		}
+	}
+
+	patterns := flags.Args()
+
+	if len(patterns) == 0 {
+		patterns = []string{"."}
+	}
+
+	fset := token.NewFileSet()
+
+	files, err := parseSources(fset, patterns)
+	if err != nil {
+		fatalf("fail to parse GO sources, %v", err)
+	}
+
+	var driverRules []report.Rule
+
+	for _, rule := range rules {
+		driverRules = append(driverRules, report.Rule{ID: rule.ID, Severity: rule.Severity})
+	}
+
+	write, err := formatter(format, files, driverRules)
+	if err != nil {
+		fatalf("%v", err)
+	}
+
+	l := lint.NewLinter(fset, rules)
+
+	var results []*report.Result
+
+	for _, file := range files {
+		found, err := l.Lint(file.Name, file.File, file.Src)
+		if err != nil {
+			fatalf("fail to lint `%s`, %v", file.Name, err)
+		}
+
+		results = append(results, found...)
+	}
+
+	if err := write(os.Stdout, results); err != nil {
+		fatalf("fail to write results, %v", err)
+	}
+
+	if len(results) > 0 {
+		os.Exit(exitMatched)
+	}
+}
@@ -0,0 +1,493 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/parser"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/flier/astq/pkg/report"
+	"github.com/flier/astq/pkg/rewrite"
+	"github.com/flier/astq/pkg/selector"
+)
+
+const goExt = ".go"
+
+const (
+	exitMatched = 1 // any node matched with -fail-on-match, or any rule reported by lint
+	exitError   = 2 // fail to parse or evaluate the query, or to read the sources
+)
+
+var (
+	generator   = &Generator{filepath.Base(os.Args[0]), "1.0"}
+	queryFile   string
+	libraries   stringList
+	format      string
+	failOnMatch bool
+	explain     bool
+	workers     int
+	maxResults  int
+	replacement string
+	showDiff    bool
+	writeFiles  bool
+	showVersion bool
+	parseMode   = parser.AllErrors | parser.ParseComments
+)
+
+type Generator struct {
+	Name, Version string
+}
+
+func (g *Generator) String() string {
+	return fmt.Sprintf("%s v%s", g.Name, g.Version)
+}
+
+func init() {
+	flag.StringVar(&queryFile, "q", "", "read the query from file")
+	flag.Var(&libraries, "lib", "load the named queries from the library file, could be repeated")
+	flag.StringVar(&format, "format", "text", "output format: text, json, jsonl or sarif")
+	flag.BoolVar(&failOnMatch, "fail-on-match", false, "exit with status 1 when any node matched")
+	flag.BoolVar(&explain, "explain", false, "explain how the nodes are matched by each step to stderr")
+	flag.IntVar(&workers, "j", 0, "the number of files evaluated concurrently, defaults to the number of CPUs")
+	flag.IntVar(&maxResults, "max", 0, "stop after the number of matched nodes, unlimited if zero")
+	flag.StringVar(&replacement, "rewrite", "", "replace the matched nodes with the template, e.g. `errors.New($1)`")
+	flag.BoolVar(&showDiff, "d", false, "display the unified diff of rewritten files instead of their sources")
+	flag.BoolVar(&writeFiles, "w", false, "write the rewritten sources back to the files instead of stdout")
+	flag.BoolVar(&showVersion, "v", false, "show the version")
+
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] <query> [package|file|directory ...]\n", generator.Name)
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s [flags] -q <file> [package|file|directory ...]\n", generator.Name)
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s lint [flags] [package|file|directory ...]\n", generator.Name)
+		fmt.Fprintf(flag.CommandLine.Output(), "       %s repl [flags] [package|file|directory ...]\n\n", generator.Name)
+
+		flag.PrintDefaults()
+
+		fmt.Fprintf(flag.CommandLine.Output(), "\nThe exit status is %d if any node matched with -fail-on-match, or %d on errors.\n", exitMatched, exitError)
+	}
+}
+
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+
+	return nil
+}
+
+// fatalf logs the error and exits with the error status, which is distinguished from the status of matches.
+func fatalf(format string, args ...interface{}) {
+	log.Printf(format, args...)
+	os.Exit(exitError)
+}
+
+// SourceFile is a parsed GO source file with its content.
+type SourceFile struct {
+	*ast.File
+
+	Name string
+	Src  []byte
+}
+
+func parseQuery(args []string) (q selector.Query, patterns []string, err error) {
+	var src string
+
+	if queryFile != "" {
+		var buf []byte
+
+		if queryFile == "-" {
+			buf, err = ioutil.ReadAll(os.Stdin)
+		} else {
+			buf, err = ioutil.ReadFile(queryFile)
+		}
+
+		if err != nil {
+			return
+		}
+
+		src, patterns = string(buf), args
+	} else if len(args) > 0 {
+		src, patterns = args[0], args[1:]
+	} else {
+		return nil, nil, fmt.Errorf("missing query")
+	}
+
+	if len(patterns) == 0 {
+		patterns = []string{"."}
+	}
+
+	lib := selector.NewLibrary()
+
+	for _, filename := range libraries {
+		if err = lib.Load(filename); err != nil {
+			return
+		}
+	}
+
+	q, err = lib.ParseQuery(src)
+
+	return
+}
+
+// loadLibraries loads the library files of -lib flags, or exits on errors.
+func loadLibraries() *selector.Library {
+	lib := selector.NewLibrary()
+
+	for _, filename := range libraries {
+		if err := lib.Load(filename); printSyntaxErrors(os.Stderr, err) {
+			os.Exit(exitError)
+		} else if err != nil {
+			fatalf("fail to load library, %v", err)
+		}
+	}
+
+	return lib
+}
+
+// printSyntaxErrors prints the syntax errors of query or library file with the carets,
+// and reports whether any error is printed.
+func printSyntaxErrors(w io.Writer, err error) bool {
+	errs := selector.SyntaxErrors(err)
+
+	prefix := ""
+
+	if lerr, ok := err.(*selector.LibraryError); ok {
+		prefix = lerr.Filename + ":"
+	}
+
+	for _, err := range errs {
+		fmt.Fprintf(w, "%s%v\n%s\n", prefix, err, err.Caret())
+	}
+
+	return len(errs) > 0
+}
+
+func parseSources(fset *token.FileSet, patterns []string) (files []*SourceFile, err error) {
+	seen := make(map[string]bool)
+
+	for _, pattern := range patterns {
+		var filenames []string
+
+		if filenames, err = expandPattern(pattern); err != nil {
+			return
+		}
+
+		for _, filename := range filenames {
+			if seen[filename] {
+				continue
+			}
+
+			seen[filename] = true
+
+			var file *SourceFile
+
+			if file, err = parseSource(fset, filename); err != nil {
+				return
+			}
+
+			files = append(files, file)
+		}
+	}
+
+	return
+}
+
+func parseSource(fset *token.FileSet, filename string) (*SourceFile, error) {
+	var src []byte
+	var err error
+
+	if filename == "-" {
+		src, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		src, err = ioutil.ReadFile(filename)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := parser.ParseFile(fset, filename, src, parseMode)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &SourceFile{file, filename, src}, nil
+}
+
+// expandPattern returns the GO source files of the pattern,
+// which could be a file, a directory, a directory with `/...` suffix or a package import path.
+func expandPattern(pattern string) ([]string, error) {
+	if pattern == "-" {
+		return []string{"-"}, nil
+	}
+
+	if strings.HasSuffix(pattern, "/...") {
+		return walkDir(strings.TrimSuffix(pattern, "/..."))
+	}
+
+	if stat, err := os.Stat(pattern); err == nil {
+		if stat.IsDir() {
+			return listDir(pattern)
+		}
+
+		return []string{pattern}, nil
+	}
+
+	// resolve the import path in GOPATH, vendor directories or modules
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+
+	pkg, err := build.Import(pattern, cwd, build.FindOnly)
+	if err != nil || pkg.Dir == "" {
+		return nil, fmt.Errorf("package, file or directory `%s` not found", pattern)
+	}
+
+	return listDir(pkg.Dir)
+}
+
+func listDir(dir string) ([]string, error) {
+	infos, err := ioutil.ReadDir(dir)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var filenames []string
+
+	for _, info := range infos {
+		if !info.IsDir() && strings.HasSuffix(info.Name(), goExt) {
+			filenames = append(filenames, filepath.Join(dir, info.Name()))
+		}
+	}
+
+	return filenames, nil
+}
+
+func walkDir(root string) (filenames []string, err error) {
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			name := info.Name()
+
+			if path != root && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if strings.HasSuffix(path, goExt) {
+			filenames = append(filenames, path)
+		}
+
+		return nil
+	})
+
+	sort.Strings(filenames)
+
+	return
+}
+
+// formatter returns the writer of the output format, the SARIF logs are produced by this command with the rules.
+func formatter(name string, files []*SourceFile, rules []report.Rule) (report.Formatter, error) {
+	if name == "sarif" {
+		sarif := &report.SARIF{
+			ToolName:    generator.Name,
+			ToolVersion: generator.Version,
+			Rules:       rules,
+			Sources:     make(map[string][]byte),
+		}
+
+		for _, file := range files {
+			sarif.Sources[file.Name] = file.Src
+		}
+
+		return sarif.Write, nil
+	}
+
+	write, ok := report.Formats[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown output format `%s`", name)
+	}
+
+	return write, nil
+}
+
+// rewriteFiles replaces the matched nodes of files with the template, and returns the number of changed files.
+func rewriteFiles(fset *token.FileSet, q selector.Query, files []*SourceFile) (changed int, err error) {
+	t, err := rewrite.ParseTemplate(replacement)
+	if err != nil {
+		return 0, err
+	}
+
+	r := rewrite.NewRewriter(fset, q, t)
+
+	for _, file := range files {
+		src, err := r.Rewrite(file.File, file.Src)
+		if err != nil {
+			return changed, err
+		}
+
+		diff := rewrite.Diff(file.Name, file.Src, src, rewrite.DiffContext)
+
+		if diff != nil {
+			changed++
+		}
+
+		switch {
+		case writeFiles:
+			if diff == nil {
+				continue
+			}
+
+			fi, err := os.Stat(file.Name)
+			if err != nil {
+				return changed, err
+			}
+
+			if err := ioutil.WriteFile(file.Name, src, fi.Mode().Perm()); err != nil {
+				return changed, err
+			}
+
+		case showDiff:
+			os.Stdout.Write(diff)
+
+		default:
+			os.Stdout.Write(src)
+		}
+	}
+
+	return
+}
+
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "lint":
+			runLint(os.Args[2:])
+
+			return
+
+		case "repl":
+			runREPL(os.Args[2:])
+
+			return
+		}
+	}
+
+	flag.Parse()
+
+	if showVersion {
+		fmt.Println(generator)
+		return
+	}
+
+	if explain && (workers != 0 || maxResults != 0) {
+		fatalf("-explain evaluates the files one by one, and can't be used with -j or -max")
+	}
+
+	q, patterns, err := parseQuery(flag.Args())
+	if printSyntaxErrors(os.Stderr, err) {
+		os.Exit(exitError)
+	} else if err != nil {
+		flag.Usage()
+		fatalf("fail to parse query, %v", err)
+	}
+
+	for _, diag := range selector.Validate(q) {
+		fmt.Fprintf(os.Stderr, "warning: %s: %s\n", diag.Node, diag)
+	}
+
+	fset := token.NewFileSet()
+
+	files, err := parseSources(fset, patterns)
+	if err != nil {
+		fatalf("fail to parse GO sources, %v", err)
+	}
+
+	if len(replacement) > 0 {
+		changed, err := rewriteFiles(fset, q, files)
+		if err != nil {
+			fatalf("fail to rewrite GO sources, %v", err)
+		}
+
+		if failOnMatch && changed > 0 {
+			os.Exit(exitMatched)
+		}
+
+		return
+	}
+
+	write, err := formatter(format, files, nil)
+	if err != nil {
+		fatalf("%v", err)
+	}
+
+	e := selector.NewEvaluator(fset)
+
+	var results []*report.Result
+
+	if explain {
+		for _, file := range files {
+			x, err := e.Explain(q, file.File, nil)
+			if err != nil {
+				fatalf("fail to evaluate query on `%s`, %v", file.Name, err)
+			}
+
+			fmt.Fprintf(os.Stderr, "file: %s\n%s\n", file.Name, x)
+
+			for _, node := range x.Nodes {
+				results = append(results, report.NewResult(fset, node, file.Src))
+			}
+		}
+	} else {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		asts := make([]*ast.File, len(files))
+
+		for i, file := range files {
+			asts[i] = file.File
+		}
+
+		opts := selector.EvalOptions{Workers: workers, MaxResults: maxResults}
+
+		err := e.EvalFiles(ctx, q, asts, opts, func(m selector.FileMatch) error {
+			results = append(results, report.NewResult(fset, m.Node, files[m.File].Src))
+
+			return nil
+		})
+		if err != nil {
+			fatalf("fail to evaluate query, %v", err)
+		}
+	}
+
+	if err := write(os.Stdout, results); err != nil {
+		fatalf("fail to write results, %v", err)
+	}
+
+	if failOnMatch && len(results) > 0 {
+		os.Exit(exitMatched)
+	}
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const testSource = `package test
+
+func Foo(a, b int) int {
+	return a + b
+}
+
+func Bar() int {
+	return Foo(1, 2)
+}
+`
+
+const testRewritten = `package test
+
+func Foo(a, b int) int {
+	return a + b
+}
+
+func Bar() int {
+	return Add(1, 0)
+}
+`
+
+const testQuery = `// CallExpr ! [ /:Fun Ident [ @name == "Foo" ] ] /:Args * ! [1]`
+
+func TestMain(m *testing.M) {
+	// run the command instead of tests when invoked by astq()
+	if os.Getenv("ASTQ_TEST_MAIN") == "1" {
+		main()
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+// astq runs the command in the directory, and returns its stdout and exit code.
+func astq(dir string, args ...string) (string, int) {
+	stdout, _, code := run(dir, args...)
+
+	return stdout, code
+}
+
+// astqStderr runs the command in the directory, and returns its stderr and exit code.
+func astqStderr(dir string, args ...string) (string, int) {
+	_, stderr, code := run(dir, args...)
+
+	return stderr, code
+}
+
+func run(dir string, args ...string) (string, string, int) {
+	exe, err := os.Executable()
+
+	if err != nil {
+		panic(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command(exe, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "ASTQ_TEST_MAIN=1")
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	err = cmd.Run()
+
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return stdout.String(), stderr.String(), exitErr.ExitCode()
+	} else if err != nil {
+		panic(err)
+	}
+
+	return stdout.String(), stderr.String(), 0
+}
+
+// writeTestFiles writes the files into a temporary directory, and returns the directory.
+func writeTestFiles(files map[string]string) string {
+	dir, err := ioutil.TempDir("", "astq")
+
+	if err != nil {
+		panic(err)
+	}
+
+	for name, src := range files {
+		filename := filepath.Join(dir, name)
+
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			panic(err)
+		}
+
+		if err := ioutil.WriteFile(filename, []byte(src), 0644); err != nil {
+			panic(err)
+		}
+	}
+
+	return dir
+}
+
+func TestExpandPattern(t *testing.T) {
+	Convey("Given the source files", t, func() {
+		dir := writeTestFiles(map[string]string{
+			"a.go":              testSource,
+			"a.txt":             "",
+			"b/b.go":            testSource,
+			"b/testdata/c.go":   testSource,
+			"b/vendor/d/d.go":   testSource,
+			"b/_skipped/e.go":   testSource,
+			"b/.hidden/f.go":    testSource,
+			"b/nested/g/g.go":   testSource,
+			"b/nested/g/g.json": "",
+		})
+
+		defer os.RemoveAll(dir)
+
+		Convey("When expand the patterns", func() {
+			var patterns = map[string][]string{
+				"-":                                     {"-"},
+				filepath.Join(dir, "a.go"):              {filepath.Join(dir, "a.go")},
+				dir:                                     {filepath.Join(dir, "a.go")},
+				filepath.Join(dir, "b") + "/...":        {filepath.Join(dir, "b/b.go"), filepath.Join(dir, "b/nested/g/g.go")},
+				filepath.Join(dir, "b", "testdata"):     {filepath.Join(dir, "b/testdata/c.go")},
+				filepath.Join(dir, "b/testdata/c.go"):   {filepath.Join(dir, "b/testdata/c.go")},
+				filepath.Join(dir, "b/nested") + "/...": {filepath.Join(dir, "b/nested/g/g.go")},
+			}
+
+			for pattern, filenames := range patterns {
+				found, err := expandPattern(pattern)
+
+				So(err, ShouldBeNil)
+				So(found, ShouldResemble, filenames)
+			}
+		})
+
+		Convey("When expand the package import path", func() {
+			found, err := expandPattern("github.com/flier/astq/pkg/report")
+
+			So(err, ShouldBeNil)
+			So(found, ShouldNotBeEmpty)
+			So(filepath.Base(filepath.Dir(found[0])), ShouldEqual, "report")
+		})
+
+		Convey("When expand the unknown pattern", func() {
+			_, err := expandPattern("github.com/flier/astq/pkg/unknown")
+
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "package, file or directory `github.com/flier/astq/pkg/unknown` not found")
+		})
+	})
+}
+
+func TestExitCode(t *testing.T) {
+	Convey("Given a source file", t, func() {
+		dir := writeTestFiles(map[string]string{"test.go": testSource})
+
+		defer os.RemoveAll(dir)
+
+		Convey("When the query matches with -fail-on-match", func() {
+			out, code := astq(dir, "-fail-on-match", "// FuncDecl", "test.go")
+
+			So(code, ShouldEqual, 1)
+			So(out, ShouldEqual, "test.go:3:1: func Foo(a, b int) int {\ntest.go:7:1: func Bar() int {\n")
+		})
+
+		Convey("When nothing matched with -fail-on-match", func() {
+			out, code := astq(dir, "-fail-on-match", "// GoStmt", "test.go")
+
+			So(code, ShouldEqual, 0)
+			So(out, ShouldBeEmpty)
+		})
+
+		Convey("When the query has syntax errors", func() {
+			_, code := astq(dir, "// FuncDecl [", "test.go")
+
+			So(code, ShouldEqual, 2)
+		})
+
+		Convey("When the library has syntax errors", func() {
+			So(ioutil.WriteFile(filepath.Join(dir, "bad.astq"), []byte("let a = // ;"), 0644), ShouldBeNil)
+
+			for _, args := range [][]string{
+				{"-lib", "bad.astq", "// FuncDecl", "test.go"},
+				{"lint", "-lib", "bad.astq", "test.go"},
+				{"repl", "-lib", "bad.astq", "test.go"},
+			} {
+				out, code := astqStderr(dir, args...)
+
+				So(code, ShouldEqual, 2)
+				So(out, ShouldStartWith, `bad.astq:1:12: syntax error: unexpected ";"`)
+				So(out, ShouldContainSubstring, "\nlet a = // ;\n           ^\n")
+			}
+		})
+
+		Convey("When explain with -j or -max", func() {
+			_, code := astq(dir, "-explain", "-max", "1", "// FuncDecl", "test.go")
+
+			So(code, ShouldEqual, 2)
+		})
+
+		Convey("When the source is missing with -fail-on-match", func() {
+			_, code := astq(dir, "-fail-on-match", "// FuncDecl", "missing.go")
+
+			So(code, ShouldEqual, 2)
+		})
+
+		Convey("When the evaluation fails with -fail-on-match", func() {
+			_, code := astq(dir, "-fail-on-match", "// * [ 1 / 0 ]", "test.go")
+
+			So(code, ShouldEqual, 2)
+		})
+
+		Convey("When the rewrite template is invalid with -fail-on-match", func() {
+			_, code := astq(dir, "-fail-on-match", "-rewrite", "Add($1", testQuery, "test.go")
+
+			So(code, ShouldEqual, 2)
+		})
+
+		Convey("When lint the source with SARIF output", func() {
+			rules := `{"rules": [{"id": "no-foo", "query": "// CallExpr [ /:Fun Ident [ @name == \"Foo\" ] ]", "severity": "warning", "message": "avoid Foo"}]}`
+
+			So(ioutil.WriteFile(filepath.Join(dir, ".astq.json"), []byte(rules), 0644), ShouldBeNil)
+
+			out, code := astq(dir, "lint", "-format", "sarif", "test.go")
+
+			So(code, ShouldEqual, 1)
+			So(out, ShouldContainSubstring, `"rules": [
+            {
+              "id": "no-foo",
+              "defaultConfiguration": {
+                "level": "warning"
+              }
+            }
+          ]`)
+			So(out, ShouldContainSubstring, `"columnKind": "utf16CodeUnits"`)
+		})
+
+		Convey("When lint the source without rules", func() {
+			_, code := astq(dir, "lint", "-rules", "missing.json", "test.go")
+
+			So(code, ShouldEqual, 2)
+		})
+
+		Convey("When lint the source with an invalid rule", func() {
+			rules := `{"rules": [{"id": "bad", "query": "// CallExpr [", "message": "bad"}]}`
+
+			So(ioutil.WriteFile(filepath.Join(dir, ".astq.json"), []byte(rules), 0644), ShouldBeNil)
+
+			_, code := astq(dir, "lint", "test.go")
+
+			So(code, ShouldEqual, 2)
+		})
+
+		Convey("When rewrite the source to stdout", func() {
+			out, code := astq(dir, "-rewrite", "Add($1, 0)", testQuery, "test.go")
+
+			So(code, ShouldEqual, 0)
+			So(out, ShouldEqual, testRewritten)
+
+			_, code = astq(dir, "-fail-on-match", "-rewrite", "Add($1, 0)", testQuery, "test.go")
+
+			So(code, ShouldEqual, 1)
+
+			_, code = astq(dir, "-fail-on-match", "-rewrite", "Add($1, 0)", "// GoStmt", "test.go")
+
+			So(code, ShouldEqual, 0)
+		})
+
+		Convey("When rewrite the source with -d", func() {
+			out, code := astq(dir, "-d", "-rewrite", "Add($1, 0)", testQuery, "test.go")
+
+			So(code, ShouldEqual, 0)
+			So(out, ShouldEqual, "--- test.go.orig\n+++ test.go\n@@ -5,5 +5,5 @@\n }\n \n func Bar() int {\n-\treturn Foo(1, 2)\n+\treturn Add(1, 0)\n }\n")
+
+			src, err := ioutil.ReadFile(filepath.Join(dir, "test.go"))
+
+			So(err, ShouldBeNil)
+			So(string(src), ShouldEqual, testSource)
+		})
+
+		Convey("When rewrite the source with -w", func() {
+			out, code := astq(dir, "-w", "-rewrite", "Add($1, 0)", testQuery, "test.go")
+
+			So(code, ShouldEqual, 0)
+			So(out, ShouldBeEmpty)
+
+			src, err := ioutil.ReadFile(filepath.Join(dir, "test.go"))
+
+			So(err, ShouldBeNil)
+			So(string(src), ShouldEqual, testRewritten)
+		})
+	})
+}
@@ -0,0 +1,347 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/flier/astq/pkg/query"
+	"github.com/flier/astq/pkg/report"
+	"github.com/flier/astq/pkg/selector"
+)
+
+const (
+	historyFile = ".astq_history"
+	maxHistory  = 1000
+)
+
+const replHelp = `Enter a query to evaluate it from the context node, or one of the commands:
+
+  :show <n>   show the source of the nth match
+  :dump <n>   dump the AST of the nth match
+  :cd <n>     enter the nth match as the context node for relative axes, e.g. ./
+  :cd ..      leave the context node
+  :cd         leave all the context nodes
+  :history    list the history of queries
+  !!, !<n>    evaluate the last or nth query in history
+  :help       show this help
+  :quit       exit
+`
+
+// replMatch is a node matched in the source file.
+type replMatch struct {
+	file  *SourceFile
+	index *selector.Index
+	node  ast.Node
+}
+
+type repl struct {
+	fset    *token.FileSet
+	files   []*SourceFile
+	indexes []*selector.Index
+	lib     *selector.Library
+	e       *selector.Evaluator
+	out     io.Writer
+	history []string
+	matches []replMatch
+	stack   []replMatch // the context nodes entered by :cd
+}
+
+// runREPL loads the GO sources once, and evaluates the queries read from stdin interactively.
+func runREPL(args []string) {
+	flags := flag.NewFlagSet(generator.Name+" repl", flag.ExitOnError)
+
+	flags.Var(&libraries, "lib", "load the named queries from the library file, could be repeated")
+
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s repl [flags] [package|file|directory ...]\n\n", generator.Name)
+
+		flags.PrintDefaults()
+	}
+
+	flags.Parse(args)
+
+	lib := loadLibraries()
+
+	patterns := flags.Args()
+
+	if len(patterns) == 0 {
+		patterns = []string{"."}
+	}
+
+	fset := token.NewFileSet()
+
+	files, err := parseSources(fset, patterns)
+	if err != nil {
+		fatalf("fail to parse GO sources, %v", err)
+	}
+
+	r := &repl{fset: fset, files: files, lib: lib, e: selector.NewEvaluator(fset), out: os.Stdout}
+
+	for _, file := range files {
+		r.indexes = append(r.indexes, selector.NewIndex(file.File))
+	}
+
+	r.loadHistory()
+
+	fmt.Fprintf(r.out, "%s, %d file(s) loaded, enter :help for commands\n", generator, len(files))
+
+	r.run(os.Stdin)
+}
+
+func (r *repl) run(in io.Reader) {
+	scanner := bufio.NewScanner(in)
+
+	for {
+		fmt.Fprint(r.out, r.prompt())
+
+		if !scanner.Scan() {
+			fmt.Fprintln(r.out)
+
+			return
+		}
+
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" {
+			continue
+		}
+
+		if line == ":quit" || line == ":q" {
+			return
+		}
+
+		if err := r.exec(line); err != nil {
+			fmt.Fprintf(r.out, "error: %v\n", err)
+		}
+	}
+}
+
+func (r *repl) prompt() string {
+	if n := len(r.stack); n > 0 {
+		return fmt.Sprintf("astq %s> ", r.position(r.stack[n-1].node))
+	}
+
+	return "astq> "
+}
+
+func (r *repl) exec(line string) error {
+	if strings.HasPrefix(line, "!") {
+		s, err := r.recall(line)
+
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintln(r.out, s)
+
+		line = s
+	}
+
+	if !strings.HasPrefix(line, ":") {
+		r.addHistory(line)
+
+		return r.eval(line)
+	}
+
+	fields := strings.Fields(line)
+	cmd, args := fields[0], fields[1:]
+
+	switch cmd {
+	case ":help", ":h":
+		fmt.Fprint(r.out, replHelp)
+
+	case ":history":
+		for i, s := range r.history {
+			fmt.Fprintf(r.out, "%5d  %s\n", i+1, s)
+		}
+
+	case ":show", ":dump":
+		m, err := r.match(args)
+
+		if err != nil {
+			return err
+		}
+
+		if cmd == ":show" {
+			fmt.Fprintf(r.out, "%s\n", report.NewResult(r.fset, m.node, m.file.Src).Snippet)
+		} else {
+			fmt.Fprint(r.out, query.Dump(m.node))
+		}
+
+	case ":cd":
+		switch {
+		case len(args) == 0:
+			r.stack = nil
+
+		case args[0] == "..":
+			if len(r.stack) > 0 {
+				r.stack = r.stack[:len(r.stack)-1]
+			}
+
+		default:
+			m, err := r.match(args)
+
+			if err != nil {
+				return err
+			}
+
+			r.stack = append(r.stack, m)
+		}
+
+	default:
+		return fmt.Errorf("unknown command `%s`, enter :help for commands", cmd)
+	}
+
+	return nil
+}
+
+// recall returns the query in history, e.g. `!!` for the last query and `!3` for the third one.
+func (r *repl) recall(s string) (string, error) {
+	n := len(r.history)
+
+	if s != "!!" {
+		i, err := strconv.Atoi(s[1:])
+
+		if err != nil {
+			return "", fmt.Errorf("invalid history reference `%s`", s)
+		}
+
+		n = i
+	}
+
+	if n < 1 || n > len(r.history) {
+		return "", fmt.Errorf("history `%s` not found", s)
+	}
+
+	return r.history[n-1], nil
+}
+
+// match returns the nth match of the last query.
+func (r *repl) match(args []string) (replMatch, error) {
+	if len(args) != 1 {
+		return replMatch{}, fmt.Errorf("expected the number of match")
+	}
+
+	n, err := strconv.Atoi(args[0])
+
+	if err != nil || n < 1 || n > len(r.matches) {
+		return replMatch{}, fmt.Errorf("match `%s` not found, expected 1 to %d", args[0], len(r.matches))
+	}
+
+	return r.matches[n-1], nil
+}
+
+func (r *repl) eval(s string) error {
+	q, err := r.lib.ParseQuery(s)
+
+	if printSyntaxErrors(r.out, err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	for _, diag := range r.e.Validate(q) {
+		fmt.Fprintf(r.out, "warning: %s: %s\n", diag.Node, diag)
+	}
+
+	var matches []replMatch
+
+	if n := len(r.stack); n > 0 {
+		ctx := r.stack[n-1]
+
+		nodes, err := r.e.EvalIndexFrom(q, ctx.index, ctx.node, nil)
+
+		if err != nil {
+			return err
+		}
+
+		for _, node := range nodes {
+			matches = append(matches, replMatch{ctx.file, ctx.index, node})
+		}
+	} else {
+		for i, file := range r.files {
+			nodes, err := r.e.EvalIndex(q, r.indexes[i], nil)
+
+			if err != nil {
+				return fmt.Errorf("%s: %v", file.Name, err)
+			}
+
+			for _, node := range nodes {
+				matches = append(matches, replMatch{file, r.indexes[i], node})
+			}
+		}
+	}
+
+	r.matches = matches
+
+	for i, m := range matches {
+		res := report.NewResult(r.fset, m.node, m.file.Src)
+
+		fmt.Fprintf(r.out, "[%d] %s: %s: %s\n", i+1, r.position(m.node), res.Kind, res.Line())
+	}
+
+	fmt.Fprintf(r.out, "%d match(es)\n", len(matches))
+
+	return nil
+}
+
+func (r *repl) position(node ast.Node) string {
+	pos := r.fset.Position(node.Pos())
+
+	return fmt.Sprintf("%s:%d:%d", pos.Filename, pos.Line, pos.Column)
+}
+
+func historyPath() string {
+	home, err := os.UserHomeDir()
+
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, historyFile)
+}
+
+func (r *repl) loadHistory() {
+	filename := historyPath()
+
+	if filename == "" {
+		return
+	}
+
+	if buf, err := ioutil.ReadFile(filename); err == nil {
+		for _, line := range strings.Split(string(buf), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				r.history = append(r.history, line)
+			}
+		}
+	}
+
+	if n := len(r.history); n > maxHistory {
+		r.history = r.history[n-maxHistory:]
+	}
+}
+
+// addHistory appends the query to history, and saves it to the history file in home directory.
+func (r *repl) addHistory(s string) {
+	if n := len(r.history); n > 0 && r.history[n-1] == s {
+		return
+	}
+
+	r.history = append(r.history, s)
+
+	if filename := historyPath(); filename != "" {
+		if f, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600); err == nil {
+			fmt.Fprintln(f, s)
+			f.Close()
+		}
+	}
+}
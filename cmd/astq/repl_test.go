@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/flier/astq/pkg/selector"
+)
+
+func newTestREPL(out *bytes.Buffer) *repl {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "test.go", testSource, parseMode)
+
+	if err != nil {
+		panic(err)
+	}
+
+	r := &repl{
+		fset:    fset,
+		files:   []*SourceFile{{f, "test.go", []byte(testSource)}},
+		indexes: []*selector.Index{selector.NewIndex(f)},
+		lib:     selector.NewLibrary(),
+		e:       selector.NewEvaluator(fset),
+		out:     out,
+	}
+
+	r.loadHistory()
+
+	return r
+}
+
+func TestREPL(t *testing.T) {
+	Convey("Given a REPL with the loaded file", t, func() {
+		home, err := ioutil.TempDir("", "astq")
+
+		So(err, ShouldBeNil)
+
+		defer os.RemoveAll(home)
+
+		defer os.Setenv("HOME", os.Getenv("HOME"))
+
+		So(os.Setenv("HOME", home), ShouldBeNil)
+
+		Convey("When run the queries and commands", func() {
+			var sessions = map[string]string{
+				"// FuncDecl\n:cd 1\n../ *\n+/ FuncDecl\n<// Ident\n:cd ..\n+/ FuncDecl\n": `astq> [1] test.go:3:1: FuncDecl: func Foo(a, b int) int {
+[2] test.go:7:1: FuncDecl: func Bar() int {
+2 match(es)
+astq> astq test.go:3:1> [1] test.go:1:1: File: package test
+1 match(es)
+astq test.go:3:1> [1] test.go:7:1: FuncDecl: func Bar() int {
+1 match(es)
+astq test.go:3:1> [1] test.go:1:9: Ident: test
+1 match(es)
+astq test.go:3:1> astq> 0 match(es)
+astq> 
+`,
+				"// FuncDecl\n:cd 2\n// CallExpr\n:cd 1\n../ ReturnStmt ../ *\n:cd\n:show 1\n": `astq> [1] test.go:3:1: FuncDecl: func Foo(a, b int) int {
+[2] test.go:7:1: FuncDecl: func Bar() int {
+2 match(es)
+astq> astq test.go:7:1> [1] test.go:8:9: CallExpr: Foo(1, 2)
+1 match(es)
+astq test.go:7:1> astq test.go:8:9> [1] test.go:7:16: BlockStmt: {
+1 match(es)
+astq test.go:8:9> astq> {
+	return Foo(1, 2)
+}
+astq> 
+`,
+				"// FuncDecl\n:history\n!!\n!1\n!9\n!x\n:cd 5\n:show\n:unknown\n:quit\n// Ident\n": `astq> [1] test.go:3:1: FuncDecl: func Foo(a, b int) int {
+[2] test.go:7:1: FuncDecl: func Bar() int {
+2 match(es)
+astq>     1  // FuncDecl
+astq> // FuncDecl
+[1] test.go:3:1: FuncDecl: func Foo(a, b int) int {
+[2] test.go:7:1: FuncDecl: func Bar() int {
+2 match(es)
+astq> // FuncDecl
+[1] test.go:3:1: FuncDecl: func Foo(a, b int) int {
+[2] test.go:7:1: FuncDecl: func Bar() int {
+2 match(es)
+astq> error: history ` + "`!9`" + ` not found
+astq> error: invalid history reference ` + "`!x`" + `
+astq> error: match ` + "`5`" + ` not found, expected 1 to 2
+astq> error: expected the number of match
+astq> error: unknown command ` + "`:unknown`" + `, enter :help for commands
+astq> `,
+				"// Ident [ @name == \"b\" ]\n:show 2\n// FuncDecl [\n": `astq> [1] test.go:3:13: Ident: b
+[2] test.go:4:13: Ident: b
+2 match(es)
+astq> b
+astq> 1:14: syntax error: unexpected EOF, expecting identifier, string, number, float, regexp, true, false, null, axis, reference, '(', '{', ':', '@', '!', '-', '/' or '~'
+// FuncDecl [
+             ^
+astq> 
+`,
+			}
+
+			for input, output := range sessions {
+				os.Remove(filepath.Join(home, historyFile))
+
+				var out bytes.Buffer
+
+				newTestREPL(&out).run(strings.NewReader(input))
+
+				So(out.String(), ShouldEqual, output)
+			}
+		})
+
+		Convey("When dump the matched node", func() {
+			var out bytes.Buffer
+
+			newTestREPL(&out).run(strings.NewReader("// Ident [ @name == \"b\" ]\n:dump 1\n"))
+
+			So(out.String(), ShouldContainSubstring, "astq>      0  *ast.Ident {\n     1  .  NamePos: 27\n     2  .  Name: \"b\"\n")
+		})
+
+		Convey("When recall the history saved by the previous session", func() {
+			var out bytes.Buffer
+
+			newTestREPL(&out).run(strings.NewReader("// ReturnStmt\n// ReturnStmt\n// CallExpr\n"))
+
+			buf, err := ioutil.ReadFile(filepath.Join(home, historyFile))
+
+			So(err, ShouldBeNil)
+			So(string(buf), ShouldEqual, "// ReturnStmt\n// CallExpr\n")
+
+			out.Reset()
+
+			newTestREPL(&out).run(strings.NewReader(":history\n!1\n"))
+
+			So(out.String(), ShouldEqual, `astq>     1  // ReturnStmt
+    2  // CallExpr
+astq> // ReturnStmt
+[1] test.go:4:2: ReturnStmt: return a + b
+[2] test.go:8:2: ReturnStmt: return Foo(1, 2)
+2 match(es)
+astq> 
+`)
+		})
+	})
+}
@@ -0,0 +1,101 @@
+package lint
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"sort"
+	"strings"
+
+	"github.com/flier/astq/pkg/report"
+	"github.com/flier/astq/pkg/selector"
+)
+
+// ignoreDirective suppresses the rules on the line of a trailing comment, or on the line below a standalone comment,
+// e.g. `//astq:ignore no-panic,no-print the reason`
+const ignoreDirective = "//astq:ignore"
+
+// Linter checks the files with the rules.
+type Linter struct {
+	*selector.Evaluator
+
+	Rules []*Rule
+}
+
+// NewLinter returns a linter of the files parsed in the file set.
+func NewLinter(fset *token.FileSet, rules []*Rule) *Linter {
+	return &Linter{selector.NewEvaluator(fset), rules}
+}
+
+// Lint checks the file with the rules applied to it, and returns the unsuppressed results in position order.
+func (l *Linter) Lint(filename string, f *ast.File, src []byte) ([]*report.Result, error) {
+	ignored := l.ignores(f, src)
+
+	var results []*report.Result
+
+	for _, rule := range l.Rules {
+		if !rule.Applies(filename) {
+			continue
+		}
+
+		nodes, err := l.Eval(rule.query, f)
+
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %v", rule.ID, err)
+		}
+
+		for _, node := range nodes {
+			if ignored[l.Fset.Position(node.Pos()).Line][rule.ID] {
+				continue
+			}
+
+			r := report.NewResult(l.Fset, node, src)
+			r.Rule, r.Severity, r.Message = rule.ID, rule.Severity, rule.Format(node)
+
+			results = append(results, r)
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Start.Offset < results[j].Start.Offset
+	})
+
+	return results, nil
+}
+
+// ignores returns the suppressed rules of lines.
+func (l *Linter) ignores(f *ast.File, src []byte) map[int]map[string]bool {
+	ignored := make(map[int]map[string]bool)
+
+	for _, group := range f.Comments {
+		for _, c := range group.List {
+			if !strings.HasPrefix(c.Text, ignoreDirective+" ") {
+				continue
+			}
+
+			fields := strings.Fields(c.Text[len(ignoreDirective):])
+
+			if len(fields) == 0 {
+				continue
+			}
+
+			pos := l.Fset.Position(c.Pos())
+			line := pos.Line
+
+			if start := pos.Offset - pos.Column + 1; start >= 0 && pos.Offset <= len(src) && len(bytes.TrimSpace(src[start:pos.Offset])) == 0 {
+				line++
+			}
+
+			if ignored[line] == nil {
+				ignored[line] = make(map[string]bool)
+			}
+
+			for _, id := range strings.Split(fields[0], ",") {
+				ignored[line][id] = true
+			}
+		}
+	}
+
+	return ignored
+}
@@ -0,0 +1,168 @@
+package lint
+
+import (
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/flier/astq/pkg/selector"
+)
+
+const testRules = `{
+  "rules": [
+    {
+      "id": "no-panic",
+      "query": "// CallExpr [ /:Fun Ident [ @name == \"panic\" ] ]",
+      "severity": "error",
+      "message": "avoid panic in library code"
+    },
+    {
+      "id": "exported-func",
+      "query": "$exported",
+      "severity": "info",
+      "message": "exported function {name} ({method}), {unknown}",
+      "exclude": ["**/*_test.go"]
+    }
+  ]
+}`
+
+const testSource = `package test
+
+func Foo() {
+	panic("foo") //astq:ignore no-panic
+}
+
+//astq:ignore no-panic,exported-func
+func Bar() { panic("bar") }
+
+func baz() {
+	panic("baz")
+}
+`
+
+func lint(filename string) ([]string, error) {
+	lib := selector.NewLibrary()
+
+	if err := lib.Define("exported", selector.Query{selector.Path{
+		{Axis: &selector.Axis{Dir: "//"}, Match: "FuncDecl", Filter: &selector.WithAttr{ID: "exported"}},
+	}}); err != nil {
+		return nil, err
+	}
+
+	rules, err := ParseRules(strings.NewReader(testRules), lib)
+
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, testSource, parser.ParseComments)
+
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := NewLinter(fset, rules).Lint(filename, f, []byte(testSource))
+
+	if err != nil {
+		return nil, err
+	}
+
+	var msgs []string
+
+	for _, r := range results {
+		msgs = append(msgs, r.Rule+" "+r.Severity+" "+r.Line()+": "+r.Message)
+	}
+
+	return msgs, nil
+}
+
+func TestLint(t *testing.T) {
+	Convey("Given the rules", t, func() {
+		Convey("When lint a file with suppressed rules", func() {
+			msgs, err := lint("pkg/test.go")
+
+			So(err, ShouldBeNil)
+			So(msgs, ShouldResemble, []string{
+				"exported-func info func Foo() {: exported function Foo (false), {unknown}",
+				`no-panic error panic("baz"): avoid panic in library code`,
+			})
+		})
+
+		Convey("When lint an excluded file", func() {
+			msgs, err := lint("pkg/test_test.go")
+
+			So(err, ShouldBeNil)
+			So(msgs, ShouldResemble, []string{`no-panic error panic("baz"): avoid panic in library code`})
+		})
+
+		Convey("When parse the invalid rules", func() {
+			var rules = map[string]string{
+				`{"rules": [{"query": "*", "message": "x"}]}`:                                 "rule #1: missing id",
+				`{"rules": [{"id": "a", "query": "*", "message": "x"}, {"id": "a"}]}`:         "rule a: duplicated id",
+				`{"rules": [{"id": "a", "query": "*", "message": "x", "severity": "fatal"}]}`: "rule a: unknown severity `fatal`, expected error, warning or info",
+				`{"rules": [{"id": "a", "message": "x"}]}`:                                    "rule a: missing query",
+				`{"rules": [{"id": "a", "query": "*"}]}`:                                      "rule a: missing message",
+				`{"rules": [{"id": "a", "query": "*", "message": "x", "include": ["[a"]}]}`:   "rule a: invalid glob `[a`, syntax error in pattern",
+				`{"rules": [{"id": "a", "query": "$foo", "message": "x"}]}`:                   "rule a: undefined reference: $foo",
+				`{"rules": [{"id": "a", "query": "*", "message": "x", "level": "error"}]}`:    `json: unknown field "level"`,
+			}
+
+			for s, msg := range rules {
+				_, err := ParseRules(strings.NewReader(s), nil)
+
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, msg)
+			}
+		})
+
+		Convey("When match the files with globs", func() {
+			var globs = map[string]map[string]bool{
+				"*_test.go": {
+					"foo_test.go":     true,
+					"pkg/foo_test.go": true,
+					"pkg/foo.go":      false,
+				},
+				"cmd/**": {
+					"cmd/astq/main.go": true,
+					"cmd/main.go":      true,
+					"pkg/cmd/main.go":  false,
+				},
+				"**/testdata/*.go": {
+					"testdata/a.go":         true,
+					"pkg/x/testdata/a.go":   true,
+					"pkg/x/testdata/b/a.go": false,
+				},
+			}
+
+			for pattern, names := range globs {
+				for name, matched := range names {
+					So(matchGlob(pattern, name), ShouldEqual, matched)
+				}
+			}
+
+			rule := &Rule{Include: []string{"pkg/**"}, Exclude: []string{"*_test.go"}}
+
+			So(rule.Applies("./pkg/foo.go"), ShouldBeTrue)
+			So(rule.Applies("pkg/foo_test.go"), ShouldBeFalse)
+			So(rule.Applies("cmd/foo.go"), ShouldBeFalse)
+		})
+
+		Convey("When match the absolute files", func() {
+			cwd, err := os.Getwd()
+
+			So(err, ShouldBeNil)
+
+			rule := &Rule{Exclude: []string{"cmd/**"}}
+
+			So(rule.Applies(filepath.Join(cwd, "cmd", "astq", "main.go")), ShouldBeFalse)
+			So(rule.Applies(filepath.Join(cwd, "pkg", "foo.go")), ShouldBeTrue)
+			So(rule.Applies(filepath.Join(filepath.Dir(cwd), "cmd", "main.go")), ShouldBeTrue)
+		})
+	})
+}
@@ -0,0 +1,240 @@
+package lint
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/flier/astq/pkg/selector"
+)
+
+// The severities of rules.
+const (
+	Error   = "error"
+	Warning = "warning"
+	Info    = "info"
+)
+
+// Rule reports the nodes matched by the query with the message, e.g.
+//
+//	{
+//	  "id": "no-panic",
+//	  "query": "// CallExpr [ /:Fun Ident [ @name == \"panic\" ] ]",
+//	  "severity": "error",
+//	  "message": "avoid panic in library code",
+//	  "exclude": ["**/*_test.go", "cmd/**"]
+//	}
+type Rule struct {
+	ID       string   `json:"id"`
+	Query    string   `json:"query"`
+	Severity string   `json:"severity,omitempty"` // error, warning or info, defaults to warning
+	Message  string   `json:"message"`            // the message with placeholders of the node attributes, e.g. `{name}`
+	Include  []string `json:"include,omitempty"`  // the globs of files to check, defaults to all files
+	Exclude  []string `json:"exclude,omitempty"`  // the globs of files to skip
+
+	query selector.Query
+}
+
+// RuleSet is the rules file.
+type RuleSet struct {
+	Rules []*Rule `json:"rules"`
+}
+
+// LoadRules loads the rules from the file, the queries may reference the named queries in the library.
+func LoadRules(filename string, lib *selector.Library) ([]*Rule, error) {
+	f, err := os.Open(filename)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	rules, err := ParseRules(f, lib)
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+
+	return rules, nil
+}
+
+// ParseRules parses and checks the rules, the queries may reference the named queries in the library.
+func ParseRules(r io.Reader, lib *selector.Library) ([]*Rule, error) {
+	var set RuleSet
+
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(&set); err != nil {
+		return nil, err
+	}
+
+	if lib == nil {
+		lib = selector.NewLibrary()
+	}
+
+	ids := make(map[string]bool)
+
+	for i, rule := range set.Rules {
+		if rule.ID == "" {
+			return nil, fmt.Errorf("rule #%d: missing id", i+1)
+		}
+
+		if ids[rule.ID] {
+			return nil, fmt.Errorf("rule %s: duplicated id", rule.ID)
+		}
+
+		ids[rule.ID] = true
+
+		if err := rule.compile(lib); err != nil {
+			return nil, fmt.Errorf("rule %s: %v", rule.ID, err)
+		}
+	}
+
+	return set.Rules, nil
+}
+
+func (r *Rule) compile(lib *selector.Library) (err error) {
+	switch r.Severity {
+	case "":
+		r.Severity = Warning
+	case Error, Warning, Info:
+	default:
+		return fmt.Errorf("unknown severity `%s`, expected error, warning or info", r.Severity)
+	}
+
+	if r.Query == "" {
+		return fmt.Errorf("missing query")
+	}
+
+	if r.Message == "" {
+		return fmt.Errorf("missing message")
+	}
+
+	for _, pattern := range append(r.Include[:len(r.Include):len(r.Include)], r.Exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid glob `%s`, %v", pattern, err)
+		}
+	}
+
+	r.query, err = lib.ParseQuery(r.Query)
+
+	return
+}
+
+// Compiled returns the parsed query of rule.
+func (r *Rule) Compiled() selector.Query {
+	return r.query
+}
+
+// Applies reports whether the rule checks the file, the file name is matched in slash separated form,
+// relative to the working directory if the file is absolute and under the working directory.
+func (r *Rule) Applies(filename string) bool {
+	name := path.Clean(strings.Replace(relativePath(filename), "\\", "/", -1))
+
+	if len(r.Include) > 0 && !matchAny(r.Include, name) {
+		return false
+	}
+
+	return !matchAny(r.Exclude, name)
+}
+
+// relativePath returns the file name relative to the working directory,
+// or the file name as is if it is relative or outside the working directory.
+func relativePath(filename string) string {
+	if !filepath.IsAbs(filename) {
+		return filename
+	}
+
+	cwd, err := os.Getwd()
+
+	if err != nil {
+		return filename
+	}
+
+	rel, err := filepath.Rel(cwd, filename)
+
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return filename
+	}
+
+	return rel
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matchGlob(pattern, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchGlob matches the base name of file for the pattern without slash,
+// otherwise matches the path of file for the pattern, `**` matches zero or more directories.
+func matchGlob(pattern, name string) bool {
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(name))
+
+		return ok
+	}
+
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(patterns, names []string) bool {
+	for len(patterns) > 0 {
+		if patterns[0] == "**" {
+			for i := 0; i <= len(names); i++ {
+				if matchSegments(patterns[1:], names[i:]) {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		if len(names) == 0 {
+			return false
+		}
+
+		if ok, _ := path.Match(patterns[0], names[0]); !ok {
+			return false
+		}
+
+		patterns, names = patterns[1:], names[1:]
+	}
+
+	return len(names) == 0
+}
+
+var rePlaceholder = regexp.MustCompile(`\{(\w+)\}`)
+
+// Format returns the message with the placeholders replaced by the attributes of node,
+// the placeholders of undefined attributes are kept as is.
+func (r *Rule) Format(node ast.Node) string {
+	return rePlaceholder.ReplaceAllStringFunc(r.Message, func(s string) string {
+		v, ok := selector.NodeAttr(node, s[1:len(s)-1])
+
+		if !ok {
+			return s
+		}
+
+		switch v := v.(type) {
+		case selector.Str:
+			return string(v)
+		case selector.Null:
+			return ""
+		default:
+			return v.String()
+		}
+	})
+}
@@ -6,6 +6,11 @@ import (
 	"sort"
 )
 
+// Dump returns the textual dump of the AST node, skipping nil fields.
+func Dump(x interface{}) string {
+	return astDump(x)
+}
+
 func astDump(x interface{}) string {
 	var buf bytes.Buffer
 
@@ -0,0 +1,63 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Formatter writes the results in a format.
+type Formatter func(w io.Writer, results []*Result) error
+
+// Formats are the supported output formats.
+var Formats = map[string]Formatter{
+	"text":  WriteText,
+	"json":  WriteJSON,
+	"jsonl": WriteJSONL,
+	"sarif": WriteSARIF,
+}
+
+// WriteText writes the results in `file:line:col: snippet` lines,
+// or `file:line:col: severity: message (rule)` lines for the results reported by rules.
+func WriteText(w io.Writer, results []*Result) error {
+	for _, r := range results {
+		var err error
+
+		if r.Rule != "" {
+			_, err = fmt.Fprintf(w, "%s:%d:%d: %s: %s (%s)\n", r.File, r.Start.Line, r.Start.Column, r.Severity, r.Message, r.Rule)
+		} else {
+			_, err = fmt.Fprintf(w, "%s:%d:%d: %s\n", r.File, r.Start.Line, r.Start.Column, r.Line())
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteJSON writes the results as a JSON array.
+func WriteJSON(w io.Writer, results []*Result) error {
+	if results == nil {
+		results = []*Result{}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(results)
+}
+
+// WriteJSONL writes the results as JSON Lines, one result per line.
+func WriteJSONL(w io.Writer, results []*Result) error {
+	enc := json.NewEncoder(w)
+
+	for _, r := range results {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,227 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/flier/astq/pkg/selector"
+)
+
+const testSource = `package test
+
+func Foo(n int) int {
+	return n + 1
+}
+`
+
+func testResults() []*Result {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "test.go", testSource, 0)
+
+	if err != nil {
+		panic(err)
+	}
+
+	q, err := selector.ParseQuery("// FuncDecl, // BinaryExpr")
+
+	if err != nil {
+		panic(err)
+	}
+
+	nodes, err := q.Eval(f)
+
+	if err != nil {
+		panic(err)
+	}
+
+	var results []*Result
+
+	for _, node := range nodes {
+		results = append(results, NewResult(fset, node, []byte(testSource)))
+	}
+
+	return results
+}
+
+func TestResult(t *testing.T) {
+	Convey("Given the results of a query", t, func() {
+		results := testResults()
+
+		So(results, ShouldHaveLength, 2)
+
+		Convey("When build the result of a node", func() {
+			So(results[0], ShouldResemble, &Result{
+				Kind:    "FuncDecl",
+				File:    "test.go",
+				Start:   Position{3, 1, 14},
+				End:     Position{5, 2, 51},
+				Snippet: "func Foo(n int) int {\n\treturn n + 1\n}",
+				Attrs:   map[string]interface{}{"name": "Foo", "exported": true, "method": false},
+			})
+			So(results[0].Line(), ShouldEqual, "func Foo(n int) int {")
+			So(results[1].Kind, ShouldEqual, "BinaryExpr")
+			So(results[1].Snippet, ShouldEqual, "n + 1")
+			So(results[1].Attrs, ShouldResemble, map[string]interface{}{"op": "+"})
+		})
+
+		Convey("When write results as text", func() {
+			var buf bytes.Buffer
+
+			So(WriteText(&buf, results), ShouldBeNil)
+			So(buf.String(), ShouldEqual, "test.go:3:1: func Foo(n int) int {\ntest.go:4:9: n + 1\n")
+		})
+
+		Convey("When write results as JSON", func() {
+			var buf bytes.Buffer
+
+			So(WriteJSON(&buf, results), ShouldBeNil)
+
+			var decoded []*Result
+
+			So(json.Unmarshal(buf.Bytes(), &decoded), ShouldBeNil)
+			So(decoded, ShouldHaveLength, 2)
+			So(decoded[1], ShouldResemble, results[1])
+
+			buf.Reset()
+
+			So(WriteJSON(&buf, nil), ShouldBeNil)
+			So(buf.String(), ShouldEqual, "[]\n")
+		})
+
+		Convey("When write results as JSON Lines", func() {
+			var buf bytes.Buffer
+
+			So(WriteJSONL(&buf, results), ShouldBeNil)
+
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+
+			So(lines, ShouldHaveLength, 2)
+			So(lines[1], ShouldEqual, `{"kind":"BinaryExpr","file":"test.go","start":{"line":4,"column":9,"offset":44},"end":{"line":4,"column":14,"offset":49},"snippet":"n + 1","attrs":{"op":"+"}}`)
+		})
+
+		Convey("When write results as SARIF", func() {
+			var buf bytes.Buffer
+
+			So(WriteSARIF(&buf, results), ShouldBeNil)
+
+			var log sarifLog
+
+			So(json.Unmarshal(buf.Bytes(), &log), ShouldBeNil)
+			So(log.Version, ShouldEqual, "2.1.0")
+			So(log.Runs, ShouldHaveLength, 1)
+			So(log.Runs[0].Tool.Driver.Name, ShouldEqual, "astq")
+			So(log.Runs[0].Results, ShouldHaveLength, 2)
+
+			r := log.Runs[0].Results[1]
+
+			So(r.Message.Text, ShouldEqual, "BinaryExpr: n + 1")
+			So(r.Locations[0].PhysicalLocation.ArtifactLocation.URI, ShouldEqual, "test.go")
+			So(r.Locations[0].PhysicalLocation.Region, ShouldResemble, sarifRegion{
+				StartLine: 4, EndLine: 4,
+				Snippet: &sarifMessage{"n + 1"},
+			})
+			So(buf.String(), ShouldNotContainSubstring, "charOffset")
+		})
+
+		Convey("When write results as SARIF of the tool with sources", func() {
+			var buf bytes.Buffer
+
+			sarif := &SARIF{ToolName: "tool", ToolVersion: "2.0", Sources: map[string][]byte{"test.go": []byte(testSource)}}
+
+			So(sarif.Write(&buf, results), ShouldBeNil)
+
+			var log sarifLog
+
+			So(json.Unmarshal(buf.Bytes(), &log), ShouldBeNil)
+			So(log.Runs[0].Tool.Driver.Name, ShouldEqual, "tool")
+			So(log.Runs[0].Tool.Driver.Version, ShouldEqual, "2.0")
+			So(log.Runs[0].ColumnKind, ShouldEqual, "utf16CodeUnits")
+			offset, length := 44, 5
+
+			So(log.Runs[0].Results[1].Locations[0].PhysicalLocation.Region, ShouldResemble, sarifRegion{
+				StartLine: 4, StartColumn: 9, EndLine: 4, EndColumn: 14,
+				CharOffset: &offset, CharLength: &length,
+				Snippet: &sarifMessage{"n + 1"},
+			})
+		})
+
+		Convey("When write results as SARIF of the non-ASCII source", func() {
+			src := "package test\n\nvar s = \"\u00e9\U0001F600\" + \"x\"\n"
+			fset := token.NewFileSet()
+			f, err := parser.ParseFile(fset, "test.go", src, 0)
+
+			So(err, ShouldBeNil)
+
+			var node ast.Node
+
+			ast.Inspect(f, func(n ast.Node) bool {
+				if _, ok := n.(*ast.BinaryExpr); ok {
+					node = n
+				}
+
+				return node == nil
+			})
+
+			var buf bytes.Buffer
+
+			sarif := &SARIF{ToolName: "tool", ToolVersion: "2.0", Sources: map[string][]byte{"test.go": []byte(src)}}
+
+			So(sarif.Write(&buf, []*Result{NewResult(fset, node, []byte(src))}), ShouldBeNil)
+
+			var log sarifLog
+
+			So(json.Unmarshal(buf.Bytes(), &log), ShouldBeNil)
+
+			offset, length := 22, 11
+
+			So(log.Runs[0].Results[0].Locations[0].PhysicalLocation.Region, ShouldResemble, sarifRegion{
+				StartLine: 3, StartColumn: 9, EndLine: 3, EndColumn: 20,
+				CharOffset: &offset, CharLength: &length,
+				Snippet: &sarifMessage{"\"\u00e9\U0001F600\" + \"x\""},
+			})
+		})
+
+		Convey("When count the columns in UTF-16 code units", func() {
+			src := []byte("a := \"\u00e9\U0001F600\" + b\n")
+
+			So(utf16Column(src, Position{1, 1, 0}), ShouldEqual, 1)
+			So(utf16Column(src, Position{1, 17, 16}), ShouldEqual, 14)
+			So(utf16Column(src, Position{1, 18, 17}), ShouldEqual, 15)
+			So(utf16Column(nil, Position{1, 17, 16}), ShouldEqual, 0)
+			So(utf16Len(src), ShouldEqual, 15)
+		})
+
+		Convey("When write results reported by rules", func() {
+			results[1].Rule, results[1].Severity, results[1].Message = "no-add", "error", "avoid addition"
+
+			var buf bytes.Buffer
+
+			So(WriteText(&buf, results), ShouldBeNil)
+			So(buf.String(), ShouldEqual, "test.go:3:1: func Foo(n int) int {\ntest.go:4:9: error: avoid addition (no-add)\n")
+
+			buf.Reset()
+
+			sarif := &SARIF{ToolName: "astq", ToolVersion: "1.0", Rules: []Rule{{"no-add", "error"}}}
+
+			So(sarif.Write(&buf, results), ShouldBeNil)
+
+			var log sarifLog
+
+			So(json.Unmarshal(buf.Bytes(), &log), ShouldBeNil)
+			So(log.Runs[0].Tool.Driver.Rules, ShouldResemble, []sarifRule{{"no-add", &sarifConfiguration{"error"}}})
+
+			r := log.Runs[0].Results[1]
+
+			So(r.RuleID, ShouldEqual, "no-add")
+			So(r.Level, ShouldEqual, "error")
+			So(r.Message.Text, ShouldEqual, "avoid addition")
+		})
+	})
+}
@@ -0,0 +1,95 @@
+package report
+
+import (
+	"go/ast"
+	"go/token"
+	"reflect"
+	"strings"
+
+	"github.com/flier/astq/pkg/selector"
+)
+
+// Position is the location of a node boundary in the source file.
+type Position struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	Offset int `json:"offset"`
+}
+
+// Result is a matched node, or the node reported by a rule.
+type Result struct {
+	Kind     string                 `json:"kind"`
+	File     string                 `json:"file"`
+	Start    Position               `json:"start"`
+	End      Position               `json:"end"`
+	Snippet  string                 `json:"snippet"`
+	Attrs    map[string]interface{} `json:"attrs,omitempty"`
+	Rule     string                 `json:"rule,omitempty"`
+	Severity string                 `json:"severity,omitempty"` // error, warning or info
+	Message  string                 `json:"message,omitempty"`
+}
+
+// NewResult returns the result of the matched node, the snippet is sliced from src if present.
+func NewResult(fset *token.FileSet, node ast.Node, src []byte) *Result {
+	start := fset.Position(node.Pos())
+	end := fset.Position(node.End())
+
+	r := &Result{
+		Kind:  kindOf(node),
+		File:  start.Filename,
+		Start: Position{start.Line, start.Column, start.Offset},
+		End:   Position{end.Line, end.Column, end.Offset},
+	}
+
+	if start.Offset >= 0 && start.Offset <= end.Offset && end.Offset <= len(src) {
+		r.Snippet = string(src[start.Offset:end.Offset])
+	}
+
+	if attrs := selector.NodeAttrs(node); len(attrs) > 0 {
+		r.Attrs = make(map[string]interface{})
+
+		for name, v := range attrs {
+			r.Attrs[name] = valueOf(v)
+		}
+	}
+
+	return r
+}
+
+// Line returns the first line of the snippet.
+func (r *Result) Line() string {
+	s := r.Snippet
+
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i]
+	}
+
+	return strings.TrimSpace(s)
+}
+
+func kindOf(node ast.Node) string {
+	t := reflect.TypeOf(node)
+
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t.Name()
+}
+
+func valueOf(v selector.Value) interface{} {
+	switch v := v.(type) {
+	case selector.Num:
+		return int64(v)
+	case selector.Float:
+		return float64(v)
+	case selector.Str:
+		return string(v)
+	case selector.Bool:
+		return bool(v)
+	case selector.Null:
+		return nil
+	default:
+		return v.String()
+	}
+}
@@ -0,0 +1,201 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+)
+
+const (
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion = "2.1.0"
+)
+
+const (
+	defaultToolName    = "astq"
+	defaultToolVersion = "1.0"
+)
+
+const matchRuleID = "match"
+
+// Rule describes the rule of results in the SARIF tool driver.
+type Rule struct {
+	ID       string
+	Severity string
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool       sarifTool     `json:"tool"`
+	ColumnKind string        `json:"columnKind"`
+	Results    []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name    string      `json:"name"`
+	Version string      `json:"version"`
+	Rules   []sarifRule `json:"rules,omitempty"`
+}
+
+type sarifRule struct {
+	ID                   string              `json:"id"`
+	DefaultConfiguration *sarifConfiguration `json:"defaultConfiguration,omitempty"`
+}
+
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+type sarifResult struct {
+	RuleID     string                 `json:"ruleId"`
+	Level      string                 `json:"level"`
+	Message    sarifMessage           `json:"message"`
+	Locations  []sarifLocation        `json:"locations"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+	Region           sarifRegion           `json:"region"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+// sarifRegion is the region of result, the columns and chars are omitted if unknown.
+type sarifRegion struct {
+	StartLine   int           `json:"startLine"`
+	StartColumn int           `json:"startColumn,omitempty"`
+	EndLine     int           `json:"endLine"`
+	EndColumn   int           `json:"endColumn,omitempty"`
+	CharOffset  *int          `json:"charOffset,omitempty"`
+	CharLength  *int          `json:"charLength,omitempty"`
+	Snippet     *sarifMessage `json:"snippet,omitempty"`
+}
+
+// SARIF writes the results as a SARIF 2.1.0 log produced by the tool.
+type SARIF struct {
+	ToolName    string
+	ToolVersion string
+	Rules       []Rule            // the rules described by the tool driver, defaults to the rule of matched nodes
+	Sources     map[string][]byte // the sources of files to count the columns and chars in UTF-16 code units
+}
+
+// WriteSARIF writes the results as a SARIF 2.1.0 log produced by astq.
+func WriteSARIF(w io.Writer, results []*Result) error {
+	return (&SARIF{ToolName: defaultToolName, ToolVersion: defaultToolVersion}).Write(w, results)
+}
+
+// Write writes the results as a SARIF 2.1.0 log, the columns and chars are counted in UTF-16 code units
+// as SARIF defaults, and omitted if the source of file is absent.
+func (s *SARIF) Write(w io.Writer, results []*Result) error {
+	rules := []sarifRule{{ID: matchRuleID}}
+
+	if len(s.Rules) > 0 {
+		rules = nil
+
+		for _, rule := range s.Rules {
+			rules = append(rules, sarifRule{rule.ID, &sarifConfiguration{sarifLevel(rule.Severity)}})
+		}
+	}
+
+	run := sarifRun{
+		Tool: sarifTool{sarifDriver{
+			Name:    s.ToolName,
+			Version: s.ToolVersion,
+			Rules:   rules,
+		}},
+		ColumnKind: "utf16CodeUnits",
+		Results:    []sarifResult{},
+	}
+
+	for _, r := range results {
+		region := sarifRegion{StartLine: r.Start.Line, EndLine: r.End.Line}
+
+		if src, ok := s.Sources[r.File]; ok && 0 <= r.Start.Offset && r.Start.Offset <= r.End.Offset && r.End.Offset <= len(src) {
+			offset, length := utf16Len(src[:r.Start.Offset]), utf16Len(src[r.Start.Offset:r.End.Offset])
+
+			region.StartColumn, region.EndColumn = utf16Column(src, r.Start), utf16Column(src, r.End)
+			region.CharOffset, region.CharLength = &offset, &length
+		}
+
+		if r.Snippet != "" {
+			region.Snippet = &sarifMessage{r.Snippet}
+		}
+
+		result := sarifResult{
+			RuleID:  matchRuleID,
+			Level:   "note",
+			Message: sarifMessage{fmt.Sprintf("%s: %s", r.Kind, r.Line())},
+			Locations: []sarifLocation{{sarifPhysicalLocation{
+				ArtifactLocation: sarifArtifactLocation{filepath.ToSlash(r.File)},
+				Region:           region,
+			}}},
+			Properties: r.Attrs,
+		}
+
+		if r.Rule != "" {
+			result.RuleID, result.Level, result.Message.Text = r.Rule, sarifLevel(r.Severity), r.Message
+		}
+
+		run.Results = append(run.Results, result)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(sarifLog{sarifSchema, sarifVersion, []sarifRun{run}})
+}
+
+// utf16Column returns the column of the position counted in UTF-16 code units, or 0 if the position is out of source.
+func utf16Column(src []byte, pos Position) int {
+	start := pos.Offset - pos.Column + 1
+
+	if start < 0 || pos.Offset > len(src) {
+		return 0
+	}
+
+	return utf16Len(src[start:pos.Offset]) + 1
+}
+
+// utf16Len returns the length of UTF-8 encoded text in UTF-16 code units.
+func utf16Len(b []byte) (n int) {
+	for _, r := range string(b) {
+		if r >= 0x10000 {
+			n += 2 // surrogate pair
+		} else {
+			n++
+		}
+	}
+
+	return
+}
+
+// sarifLevel returns the SARIF level of the severity.
+func sarifLevel(severity string) string {
+	switch severity {
+	case "error", "warning":
+		return severity
+	default:
+		return "note"
+	}
+}
@@ -0,0 +1,244 @@
+package rewrite
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// DiffContext is the default number of unchanged lines around the changes in unified diff.
+const DiffContext = 3
+
+type lineOp struct {
+	kind byte // ' ' for unchanged line, '-' for deleted line, '+' for inserted line
+	line string
+}
+
+// Diff returns the unified diff between the original and rewritten source of the file
+// with the number of unchanged context lines around the changes, or nil if they are equal.
+func Diff(filename string, src, dst []byte, context int) []byte {
+	if bytes.Equal(src, dst) {
+		return nil
+	}
+
+	ops := diffLines(splitLines(string(src)), splitLines(string(dst)))
+
+	buf := new(bytes.Buffer)
+
+	fmt.Fprintf(buf, "--- %s.orig\n+++ %s\n", filename, filename)
+
+	for _, h := range hunks(ops, context) {
+		writeHunk(buf, ops, h[0], h[1])
+	}
+
+	return buf.Bytes()
+}
+
+// splitLines splits the text into lines with the trailing newline.
+func splitLines(s string) (lines []string) {
+	for len(s) > 0 {
+		i := strings.IndexByte(s, '\n')
+
+		if i < 0 {
+			lines = append(lines, s)
+
+			break
+		}
+
+		lines = append(lines, s[:i+1])
+		s = s[i+1:]
+	}
+
+	return
+}
+
+// diffLines returns the edit script of the longest common subsequence of lines.
+func diffLines(a, b []string) []lineOp {
+	var prefix, suffix []lineOp
+
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		prefix = append(prefix, lineOp{' ', a[0]})
+		a, b = a[1:], b[1:]
+	}
+
+	n := 0
+
+	for n < len(a) && n < len(b) && a[len(a)-1-n] == b[len(b)-1-n] {
+		n++
+	}
+
+	for _, line := range a[len(a)-n:] {
+		suffix = append(suffix, lineOp{' ', line})
+	}
+
+	a, b = a[:len(a)-n], b[:len(b)-n]
+
+	ops := lcsOps(prefix, a, b)
+
+	return append(ops, suffix...)
+}
+
+// lcsOps appends the edit script of the longest common subsequence of lines to ops in linear space,
+// which divides the lines of a in half at the line of b splitting the subsequence (Hirschberg's algorithm).
+func lcsOps(ops []lineOp, a, b []string) []lineOp {
+	switch {
+	case len(a) == 0:
+		for _, line := range b {
+			ops = append(ops, lineOp{'+', line})
+		}
+
+		return ops
+
+	case len(b) == 0:
+		for _, line := range a {
+			ops = append(ops, lineOp{'-', line})
+		}
+
+		return ops
+
+	case len(a) == 1:
+		for j, line := range b {
+			if line == a[0] {
+				ops = lcsOps(ops, nil, b[:j])
+				ops = append(ops, lineOp{' ', line})
+
+				return lcsOps(ops, nil, b[j+1:])
+			}
+		}
+
+		ops = append(ops, lineOp{'-', a[0]})
+
+		return lcsOps(ops, nil, b)
+	}
+
+	mid := len(a) / 2
+
+	head := lcsLengths(a[:mid], b)
+	tail := lcsLengths(reverseLines(a[mid:]), reverseLines(b))
+
+	split, longest := 0, -1
+
+	for j := 0; j <= len(b); j++ {
+		if n := head[j] + tail[len(b)-j]; n > longest {
+			split, longest = j, n
+		}
+	}
+
+	ops = lcsOps(ops, a[:mid], b[:split])
+
+	return lcsOps(ops, a[mid:], b[split:])
+}
+
+// lcsLengths returns the lengths of the longest common subsequence of a and each prefix of b.
+func lcsLengths(a, b []string) []int {
+	prev, cur := make([]int, len(b)+1), make([]int, len(b)+1)
+
+	for i := range a {
+		for j := range b {
+			switch {
+			case a[i] == b[j]:
+				cur[j+1] = prev[j] + 1
+			case prev[j+1] >= cur[j]:
+				cur[j+1] = prev[j+1]
+			default:
+				cur[j+1] = cur[j]
+			}
+		}
+
+		prev, cur = cur, prev
+	}
+
+	return prev
+}
+
+func reverseLines(lines []string) []string {
+	reversed := make([]string, len(lines))
+
+	for i, line := range lines {
+		reversed[len(lines)-1-i] = line
+	}
+
+	return reversed
+}
+
+// hunks returns the ranges of ops with the changes and their context lines.
+func hunks(ops []lineOp, context int) (ranges [][2]int) {
+	for i := 0; i < len(ops); i++ {
+		if ops[i].kind == ' ' {
+			continue
+		}
+
+		start := i - context
+
+		if start < 0 {
+			start = 0
+		}
+
+		if n := len(ranges); n > 0 && start <= ranges[n-1][1] {
+			start = ranges[n-1][0]
+			ranges = ranges[:n-1]
+		}
+
+		end := i + 1
+
+		for end < len(ops) && ops[end].kind != ' ' {
+			end++
+		}
+
+		i = end - 1
+
+		if end += context; end > len(ops) {
+			end = len(ops)
+		}
+
+		ranges = append(ranges, [2]int{start, end})
+	}
+
+	return
+}
+
+func writeHunk(buf *bytes.Buffer, ops []lineOp, start, end int) {
+	oldStart, newStart := 1, 1
+
+	for _, op := range ops[:start] {
+		if op.kind != '+' {
+			oldStart++
+		}
+
+		if op.kind != '-' {
+			newStart++
+		}
+	}
+
+	var oldLines, newLines int
+
+	for _, op := range ops[start:end] {
+		if op.kind != '+' {
+			oldLines++
+		}
+
+		if op.kind != '-' {
+			newLines++
+		}
+	}
+
+	// the empty range starts at the line before it
+	if oldLines == 0 {
+		oldStart--
+	}
+
+	if newLines == 0 {
+		newStart--
+	}
+
+	fmt.Fprintf(buf, "@@ -%d,%d +%d,%d @@\n", oldStart, oldLines, newStart, newLines)
+
+	for _, op := range ops[start:end] {
+		buf.WriteByte(op.kind)
+		buf.WriteString(op.line)
+
+		if !strings.HasSuffix(op.line, "\n") {
+			buf.WriteString("\n\\ No newline at end of file\n")
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package rewrite
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"sort"
+
+	"github.com/flier/astq/pkg/selector"
+)
+
+// Rewriter replaces the nodes matched by the query with the expanded template.
+type Rewriter struct {
+	*selector.Evaluator
+
+	Query    selector.Query
+	Template *Template
+	Params   map[string]interface{}
+}
+
+// NewRewriter returns a rewriter of the files parsed in the file set.
+func NewRewriter(fset *token.FileSet, q selector.Query, t *Template) *Rewriter {
+	return &Rewriter{Evaluator: selector.NewEvaluator(fset), Query: q, Template: t}
+}
+
+// Edit replaces the source between the offsets.
+type Edit struct {
+	Start int
+	End   int
+	Text  string
+}
+
+// Edits returns the edits of the matched nodes in document order.
+//
+// The first node of each matched tuple is replaced, the replacements of nodes nested in or overlapped with
+// each other can't be composed, and are reported as errors.
+func (r *Rewriter) Edits(f *ast.File, src []byte) ([]Edit, error) {
+	tuples, err := r.EvalTuples(r.Query, f, r.Params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	type replacement struct {
+		Edit
+
+		node ast.Node
+	}
+
+	var replacements []replacement
+
+	for _, tuple := range tuples {
+		node := tuple[0]
+		start, end := r.Fset.Position(node.Pos()).Offset, r.Fset.Position(node.End()).Offset
+
+		if !node.Pos().IsValid() || start < 0 || start > end || end > len(src) {
+			continue
+		}
+
+		text, err := r.Template.Expand(r.Fset, src, tuple)
+
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", r.Fset.Position(node.Pos()), err)
+		}
+
+		if err := checkReplacement(node, text); err != nil {
+			return nil, fmt.Errorf("%s: invalid replacement of %s, %v", r.Fset.Position(node.Pos()), kindOf(node), err)
+		}
+
+		replacements = append(replacements, replacement{Edit{start, end, text}, node})
+	}
+
+	sort.SliceStable(replacements, func(i, j int) bool {
+		x, y := replacements[i], replacements[j]
+
+		return x.Start < y.Start || x.Start == y.Start && x.End > y.End
+	})
+
+	var edits []Edit
+
+	for i, rep := range replacements {
+		if i == 0 || rep.Start >= replacements[i-1].End {
+			edits = append(edits, rep.Edit)
+			continue
+		}
+
+		// the same node may be matched by several tuples
+		if prev := replacements[i-1]; rep.Edit != prev.Edit {
+			return nil, fmt.Errorf("%s: replacement of %s overlaps the replacement of %s at %s",
+				r.Fset.Position(rep.node.Pos()), kindOf(rep.node), kindOf(prev.node), r.Fset.Position(prev.node.Pos()))
+		}
+	}
+
+	return edits, nil
+}
+
+// Rewrite returns the formatted source with the matched nodes replaced, or the source itself if nothing matched.
+func (r *Rewriter) Rewrite(f *ast.File, src []byte) ([]byte, error) {
+	edits, err := r.Edits(f, src)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(edits) == 0 {
+		return src, nil
+	}
+
+	return Apply(src, edits)
+}
+
+// Apply replaces the source with the sorted and non-overlapped edits, and formats the result.
+func Apply(src []byte, edits []Edit) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	last := 0
+
+	for _, edit := range edits {
+		if edit.Start < last || edit.Start > edit.End || edit.End > len(src) {
+			return nil, fmt.Errorf("invalid edit of offset %d to %d, the edits must be sorted and non-overlapped", edit.Start, edit.End)
+		}
+
+		buf.Write(src[last:edit.Start])
+		buf.WriteString(edit.Text)
+		last = edit.End
+	}
+
+	buf.Write(src[last:])
+
+	return format.Source(buf.Bytes())
+}
+
+// checkReplacement parses the replacement as the kind of the replaced node.
+func checkReplacement(node ast.Node, text string) error {
+	var err error
+
+	switch node.(type) {
+	case ast.Expr:
+		_, err = parser.ParseExpr(text)
+
+	case ast.Stmt:
+		_, err = parser.ParseFile(token.NewFileSet(), "", "package p\nfunc _() {\n"+text+"\n}", 0)
+
+	case ast.Decl:
+		_, err = parser.ParseFile(token.NewFileSet(), "", "package p\n"+text, 0)
+	}
+
+	return err
+}
+
+func kindOf(node ast.Node) string {
+	t := reflect.TypeOf(node)
+
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t.Name()
+}
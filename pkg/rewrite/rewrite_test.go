@@ -0,0 +1,276 @@
+package rewrite
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/flier/astq/pkg/selector"
+)
+
+const testSource = `package test
+
+import "fmt"
+
+func Foo(a, b int) int {
+	return a + b
+}
+
+func Bar() {
+	fmt.Println(Foo(1, 2))
+	fmt.Println(Foo(Foo(3, 4), 5))
+}
+`
+
+func rewrite(query, template string) (string, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "test.go", testSource, parser.ParseComments)
+
+	if err != nil {
+		panic(err)
+	}
+
+	q, err := selector.ParseQuery(query)
+
+	if err != nil {
+		panic(err)
+	}
+
+	t, err := ParseTemplate(template)
+
+	if err != nil {
+		panic(err)
+	}
+
+	src, err := NewRewriter(fset, q, t).Rewrite(f, []byte(testSource))
+
+	return string(src), err
+}
+
+func TestTemplate(t *testing.T) {
+	Convey("Given the templates", t, func() {
+		Convey("When parse the valid templates", func() {
+			var templates = map[string][]part{
+				"foo()":          {{text: "foo()"}},
+				"$0 + 1":         {{}, {text: " + 1"}},
+				"$name($1, $$)":  {{attr: "name"}, {text: "("}, {capture: 1}, {text: ", $)"}},
+				"$2.value.$1.":   {{capture: 2, attr: "value"}, {text: "."}, {capture: 1}, {text: "."}},
+				"f($_x1, $12)":   {{text: "f("}, {attr: "_x1"}, {text: ", "}, {capture: 12}, {text: ")"}},
+				"\"$$\" + $name": {{text: "\"$\" + "}, {attr: "name"}},
+			}
+
+			for s, parts := range templates {
+				t, err := ParseTemplate(s)
+
+				So(err, ShouldBeNil)
+				So(t.parts, ShouldResemble, parts)
+				So(t.String(), ShouldEqual, s)
+			}
+		})
+
+		Convey("When parse the invalid templates", func() {
+			var templates = map[string]string{
+				"foo($)":  "invalid metavariable at offset 4, expected `$n`, `$name` or `$$`",
+				"$ + 1":   "invalid metavariable at offset 0, expected `$n`, `$name` or `$$`",
+				"a + $.x": "invalid metavariable at offset 4, expected `$n`, `$name` or `$$`",
+			}
+
+			for s, msg := range templates {
+				_, err := ParseTemplate(s)
+
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, msg)
+			}
+		})
+	})
+}
+
+func TestRewrite(t *testing.T) {
+	Convey("Given a parsed file", t, func() {
+		Convey("When rewrite the expressions with the captured nodes", func() {
+			src, err := rewrite(`// ExprStmt / CallExpr / CallExpr ! [ /:Fun Ident [ @name == "Foo" ] ] /:Args * ! [1]`, "Add($1, 0)")
+
+			So(err, ShouldBeNil)
+			So(src, ShouldEqual, `package test
+
+import "fmt"
+
+func Foo(a, b int) int {
+	return a + b
+}
+
+func Bar() {
+	fmt.Println(Add(1, 0))
+	fmt.Println(Add(Foo(3, 4), 0))
+}
+`)
+		})
+
+		Convey("When rewrite the declarations with the attributes", func() {
+			src, err := rewrite(`// FuncDecl [ @name == "Foo" ]`, "// $name is renamed\nfunc Sum(a, b int) int { return a+b }")
+
+			So(err, ShouldBeNil)
+			So(src, ShouldContainSubstring, "// Foo is renamed\nfunc Sum(a, b int) int { return a + b }\n")
+		})
+
+		Convey("When rewrite the statements", func() {
+			src, err := rewrite(`// ExprStmt [ / CallExpr /:Fun SelectorExpr [ @name == "Println" ] ]`, "_ = $0")
+
+			So(err, ShouldBeNil)
+			So(src, ShouldContainSubstring, "\t_ = fmt.Println(Foo(1, 2))\n\t_ = fmt.Println(Foo(Foo(3, 4), 5))\n")
+		})
+
+		Convey("When the replacements overlap", func() {
+			_, err := rewrite(`// CallExpr ! [ /:Fun Ident [ @name == "Foo" ] ] /:Args * ! [1]`, "Add($1, 0)")
+
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "test.go:11:18: replacement of CallExpr overlaps the replacement of CallExpr at test.go:11:14")
+
+			_, err = Apply([]byte(testSource), []Edit{{10, 20, "a"}, {15, 16, "b"}})
+
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "invalid edit of offset 15 to 16, the edits must be sorted and non-overlapped")
+		})
+
+		Convey("When the same node is matched by several tuples", func() {
+			src, err := rewrite(`// FuncDecl ! [ @name == "Foo" ] // Ident !`, "// $name is renamed\nfunc Sum(a, b int) int { return a+b }")
+
+			So(err, ShouldBeNil)
+			So(src, ShouldContainSubstring, "// Foo is renamed\nfunc Sum(a, b int) int { return a + b }\n")
+		})
+
+		Convey("When nothing matched", func() {
+			src, err := rewrite(`// GoStmt`, "$0")
+
+			So(err, ShouldBeNil)
+			So(src, ShouldEqual, testSource)
+		})
+
+		Convey("When the replacement is invalid", func() {
+			var templates = map[string]string{
+				"$0 +":    "test.go:10:14: invalid replacement of CallExpr, 1:12: expected operand, found 'EOF'",
+				"$2":      "test.go:10:14: undefined metavariable $2, only 0 node(s) captured",
+				"$0.name": "test.go:10:14: undefined metavariable $name, CallExpr has no attribute @name",
+			}
+
+			for template, msg := range templates {
+				_, err := rewrite(`// CallExpr [ /:Fun Ident ]`, template)
+
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, msg)
+			}
+		})
+	})
+}
+
+func TestDiff(t *testing.T) {
+	Convey("Given the old and new sources", t, func() {
+		Convey("When diff the equal sources", func() {
+			So(Diff("test.go", []byte(testSource), []byte(testSource), DiffContext), ShouldBeNil)
+		})
+
+		Convey("When diff the changed sources", func() {
+			src, err := rewrite(`// ExprStmt / CallExpr / CallExpr ! [ /:Fun Ident [ @name == "Foo" ] ] /:Args * ! [-1]`, "Foo($1, 0)")
+
+			So(err, ShouldBeNil)
+			So(string(Diff("test.go", []byte(testSource), []byte(src), DiffContext)), ShouldEqual, `--- test.go.orig
++++ test.go
+@@ -7,6 +7,6 @@
+ }
+`+" \n"+` func Bar() {
+-	fmt.Println(Foo(1, 2))
+-	fmt.Println(Foo(Foo(3, 4), 5))
++	fmt.Println(Foo(2, 0))
++	fmt.Println(Foo(5, 0))
+ }
+`)
+		})
+
+		Convey("When diff the distant changes without trailing newline", func() {
+			src := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj"
+			dst := "A\nb\nc\nd\ne\nf\ng\nh\ni\nJ"
+
+			So(string(Diff("x.txt", []byte(src), []byte(dst), 1)), ShouldEqual, `--- x.txt.orig
++++ x.txt
+@@ -1,2 +1,2 @@
+-a
++A
+ b
+@@ -9,2 +9,2 @@
+ i
+-j
+\ No newline at end of file
++J
+\ No newline at end of file
+`)
+			So(string(Diff("x.txt", []byte(src), []byte(dst), DiffContext)), ShouldEqual, `--- x.txt.orig
++++ x.txt
+@@ -1,4 +1,4 @@
+-a
++A
+ b
+ c
+ d
+@@ -7,4 +7,4 @@
+ g
+ h
+ i
+-j
+\ No newline at end of file
++J
+\ No newline at end of file
+`)
+		})
+
+		Convey("When diff the interleaved changes", func() {
+			src := "a\nb\nc\nd\ne\n"
+			dst := "a\nx\nc\ne\ny\n"
+
+			So(string(Diff("x.txt", []byte(src), []byte(dst), 0)), ShouldEqual, `--- x.txt.orig
++++ x.txt
+@@ -2,1 +2,1 @@
+-b
++x
+@@ -4,1 +3,0 @@
+-d
+@@ -5,0 +5,1 @@
++y
+`)
+		})
+
+		Convey("When diff the large sources", func() {
+			var src, dst []string
+
+			for i := 0; i < 5000; i++ {
+				src = append(src, fmt.Sprintf("line %d\n", i))
+
+				if i%2 == 0 {
+					dst = append(dst, fmt.Sprintf("changed %d\n", i))
+				} else {
+					dst = append(dst, fmt.Sprintf("line %d\n", i))
+				}
+			}
+
+			ops := diffLines(src, dst)
+
+			var a, b []string
+
+			for _, op := range ops {
+				if op.kind != '+' {
+					a = append(a, op.line)
+				}
+
+				if op.kind != '-' {
+					b = append(b, op.line)
+				}
+			}
+
+			So(a, ShouldResemble, src)
+			So(b, ShouldResemble, dst)
+			So(ops, ShouldHaveLength, 7500)
+		})
+	})
+}
@@ -0,0 +1,192 @@
+package rewrite
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/flier/astq/pkg/selector"
+)
+
+// Template is the GO source of replacement with metavariables bound from the matched nodes.
+//
+//	$0          the source of replaced node
+//	$1, $2 ...  the source of nodes captured by the marked steps following the replaced one
+//	$name       the attribute of replaced node, e.g. $name of FuncDecl
+//	$1.name     the attribute of captured node
+//	$$          the dollar sign
+type Template struct {
+	src   string
+	parts []part
+}
+
+// part is the literal text or metavariable of template.
+type part struct {
+	text    string
+	capture int    // the index of bound node if text is empty
+	attr    string // the attribute of bound node
+}
+
+func (p part) String() string {
+	if len(p.attr) > 0 && p.capture == 0 {
+		return "$" + p.attr
+	}
+
+	if len(p.attr) > 0 {
+		return fmt.Sprintf("$%d.%s", p.capture, p.attr)
+	}
+
+	return "$" + strconv.Itoa(p.capture)
+}
+
+// ParseTemplate parses the replacement template.
+func ParseTemplate(s string) (*Template, error) {
+	t := &Template{src: s}
+	text := new(bytes.Buffer)
+
+	for i := 0; i < len(s); {
+		c := s[i]
+
+		if c != '$' {
+			text.WriteByte(c)
+			i++
+
+			continue
+		}
+
+		if i+1 < len(s) && s[i+1] == '$' {
+			text.WriteByte('$')
+			i += 2
+
+			continue
+		}
+
+		var p part
+
+		j := i + 1
+
+		for j < len(s) && '0' <= s[j] && s[j] <= '9' {
+			j++
+		}
+
+		if j > i+1 {
+			n, err := strconv.Atoi(s[i+1 : j])
+
+			if err != nil {
+				return nil, fmt.Errorf("invalid metavariable at offset %d, %v", i, err)
+			}
+
+			p.capture = n
+
+			if j+1 < len(s) && s[j] == '.' && isIdentStart(s[j+1:]) {
+				j++
+				p.attr, j = scanIdent(s, j)
+			}
+		} else if isIdentStart(s[j:]) {
+			p.attr, j = scanIdent(s, j)
+		} else {
+			return nil, fmt.Errorf("invalid metavariable at offset %d, expected `$n`, `$name` or `$$`", i)
+		}
+
+		if text.Len() > 0 {
+			t.parts = append(t.parts, part{text: text.String()})
+			text.Reset()
+		}
+
+		t.parts = append(t.parts, p)
+		i = j
+	}
+
+	if text.Len() > 0 {
+		t.parts = append(t.parts, part{text: text.String()})
+	}
+
+	return t, nil
+}
+
+func isIdentStart(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+
+	return unicode.IsLetter(r) || r == '_'
+}
+
+func scanIdent(s string, i int) (string, int) {
+	start := i
+
+	for i < len(s) {
+		r, n := utf8.DecodeRuneInString(s[i:])
+
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			break
+		}
+
+		i += n
+	}
+
+	return s[start:i], i
+}
+
+func (t *Template) String() string {
+	return t.src
+}
+
+// Expand returns the replacement of the tuple, the first node is replaced and the rest are captured.
+func (t *Template) Expand(fset *token.FileSet, src []byte, tuple selector.Tuple) (string, error) {
+	buf := new(bytes.Buffer)
+
+	for _, p := range t.parts {
+		if p.text != "" {
+			buf.WriteString(p.text)
+
+			continue
+		}
+
+		if p.capture >= len(tuple) {
+			return "", fmt.Errorf("undefined metavariable %s, only %d node(s) captured", p, len(tuple)-1)
+		}
+
+		node := tuple[p.capture]
+
+		if len(p.attr) == 0 {
+			buf.Write(nodeSource(fset, src, node))
+
+			continue
+		}
+
+		v, ok := selector.NodeAttr(node, p.attr)
+
+		if !ok {
+			return "", fmt.Errorf("undefined metavariable %s, %s has no attribute @%s", p, kindOf(node), p.attr)
+		}
+
+		buf.WriteString(valueString(v))
+	}
+
+	return buf.String(), nil
+}
+
+// nodeSource returns the source of node sliced from the file.
+func nodeSource(fset *token.FileSet, src []byte, node ast.Node) []byte {
+	start, end := fset.Position(node.Pos()).Offset, fset.Position(node.End()).Offset
+
+	if start < 0 || start > end || end > len(src) {
+		return nil
+	}
+
+	return src[start:end]
+}
+
+func valueString(v selector.Value) string {
+	switch v := v.(type) {
+	case selector.Str:
+		return string(v)
+	case selector.Null:
+		return ""
+	default:
+		return v.String()
+	}
+}
@@ -0,0 +1,128 @@
+package selector
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+)
+
+// Tree adapts a tree of nodes to the evaluation of queries, so the queries can run on non-go/ast trees.
+//
+// The nodes are compared by identity, so they should be pointers.
+type Tree interface {
+	Children(node ast.Node) []ast.Node             // the child nodes in document order
+	Parent(node ast.Node) ast.Node                 // the parent node, or nil for the top node
+	Type(node ast.Node) string                     // the type name matched by the node test
+	Attr(node ast.Node, name string) (Value, bool) // the attribute of node, or false if the node has no such attribute
+	Pos(node ast.Node) token.Pos                   // the position of node in the file set of evaluator, or token.NoPos if unknown
+}
+
+// FieldTree is the tree which names the fields of parent node holding the child nodes,
+// so the axis types can be used, e.g. `/:Body BlockStmt`.
+type FieldTree interface {
+	Tree
+
+	Field(parent, child ast.Node) string
+}
+
+// ASTTree is the tree of go/ast nodes.
+type ASTTree struct {
+	idx *Index
+}
+
+// NewASTTree returns the go/ast tree rooted at root.
+func NewASTTree(root ast.Node) *ASTTree {
+	return &ASTTree{NewIndex(root)}
+}
+
+func (t *ASTTree) Children(node ast.Node) []ast.Node {
+	return t.idx.get().children(node)
+}
+
+func (t *ASTTree) Parent(node ast.Node) ast.Node {
+	return t.idx.get().parent(node)
+}
+
+func (t *ASTTree) Type(node ast.Node) string {
+	return typeName(node)
+}
+
+func (t *ASTTree) Attr(node ast.Node, name string) (Value, bool) {
+	return NodeAttr(node, name)
+}
+
+func (t *ASTTree) Pos(node ast.Node) token.Pos {
+	return node.Pos()
+}
+
+func (t *ASTTree) Field(parent, child ast.Node) string {
+	return fieldOf(parent, child)
+}
+
+func (t *ASTTree) AttrNames(node ast.Node) (names []string) {
+	for name := range NodeAttrs(node) {
+		names = append(names, name)
+	}
+
+	return
+}
+
+// EvalTree evaluates the query from the node of the tree with the bound query parameters.
+//
+// The tree is indexed from its top node found through the parents, so the axes may reach outside the node,
+// e.g. `../` or `+/`, and the positions of nodes are resolved by the file set of evaluator, e.g. `line()`.
+func (e *Evaluator) EvalTree(q Query, t Tree, node ast.Node, params map[string]interface{}) ([]ast.Node, error) {
+	bound, err := bindParams(q, params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ev := &evaluation{Evaluator: e, params: bound}
+
+	if at, ok := t.(*ASTTree); ok {
+		ev.tree = at.idx.get()
+	} else {
+		top := node
+
+		for parent := t.Parent(top); parent != nil; parent = t.Parent(parent) {
+			top = parent
+		}
+
+		ev.tree = newAdaptedTree(t, top)
+	}
+
+	if !ev.tree.contains(node) {
+		return nil, fmt.Errorf("%s node is not in the tree", t.Type(node))
+	}
+
+	return ev.evalQuery(q, node)
+}
+
+// newAdaptedTree indexes the tree from the root node through the adapter.
+func newAdaptedTree(adapter Tree, root ast.Node) *tree {
+	t := &tree{root: root, adapter: adapter, info: make(map[ast.Node]*treeNode)}
+
+	var walk func(n, parent ast.Node, depth int)
+
+	walk = func(n, parent ast.Node, depth int) {
+		if _, exists := t.info[n]; exists {
+			return
+		}
+
+		info := &treeNode{parent: parent, order: len(t.nodes), depth: depth}
+
+		t.nodes = append(t.nodes, n)
+		t.info[n] = info
+
+		for _, child := range adapter.Children(n) {
+			walk(child, n, depth+1)
+		}
+
+		info.last = len(t.nodes) - 1
+	}
+
+	walk(root, nil, 1)
+
+	return t
+}
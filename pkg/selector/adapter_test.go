@@ -0,0 +1,218 @@
+package selector
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/token"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const docSource = `{
+  "apiVersion": "apps/v1",
+  "kind": "Deployment",
+  "metadata": {"name": "web", "labels": {"app": "web"}},
+  "spec": {
+    "replicas": 3,
+    "paused": false,
+    "template": {
+      "spec": {
+        "containers": [
+          {"name": "nginx", "image": "nginx:latest", "cpu": 0.5},
+          {"name": "sidecar", "image": "envoy:1.0", "args": null}
+        ]
+      }
+    }
+  }
+}`
+
+func docValues(nodes []ast.Node) (values []interface{}) {
+	for _, node := range nodes {
+		n := node.(*DocNode)
+
+		if n.Type == DocObject || n.Type == DocArray {
+			values = append(values, n.Key.String())
+		} else {
+			values = append(values, n.Value)
+		}
+	}
+
+	return
+}
+
+func TestTreeAdapter(t *testing.T) {
+	Convey("Given a parsed file", t, func() {
+		fset, f := parseSource(evalSource)
+
+		tree := NewASTTree(f)
+
+		Convey("When navigate the go/ast tree", func() {
+			decl := f.Decls[1].(*ast.FuncDecl)
+
+			So(tree.Parent(decl), ShouldEqual, f)
+			So(tree.Parent(f), ShouldBeNil)
+			So(tree.Children(decl), ShouldResemble, []ast.Node{decl.Name, decl.Type, decl.Body})
+			So(tree.Field(decl, decl.Body), ShouldEqual, "Body")
+			So(tree.Type(decl), ShouldEqual, "FuncDecl")
+			So(tree.Pos(decl), ShouldEqual, decl.Pos())
+
+			v, ok := tree.Attr(decl, "name")
+
+			So(ok, ShouldBeTrue)
+			So(v, ShouldEqual, Str("Foo"))
+		})
+
+		Convey("When evaluate the queries through the go/ast tree", func() {
+			var queries = []string{
+				"// FuncDecl [ @name == \"Foo\" ] /:Body BlockStmt",
+				"// CallExpr ../ *",
+				"// Ident [ type() == \"Ident\" && attrs() == \"exported,name\" ]",
+				"//:Params Field",
+				"// ReturnStmt ../ * ../ FuncDecl",
+			}
+
+			idx := NewIndex(f)
+
+			e := NewEvaluator(nil)
+
+			for _, s := range queries {
+				q, err := ParseQuery(s)
+
+				So(err, ShouldBeNil)
+
+				expected, err := e.Eval(q, f)
+
+				So(err, ShouldBeNil)
+				So(expected, ShouldNotBeEmpty)
+
+				nodes, err := e.EvalTree(q, tree, f, nil)
+
+				So(err, ShouldBeNil)
+				So(nodes, ShouldResemble, expected)
+
+				body := f.Decls[1].(*ast.FuncDecl).Body
+
+				nodes, err = e.EvalTree(q, tree, body, nil)
+
+				So(err, ShouldBeNil)
+
+				expected, err = e.EvalIndexFrom(q, idx, body, nil)
+
+				So(err, ShouldBeNil)
+				So(nodes, ShouldResemble, expected)
+			}
+		})
+
+		Convey("When evaluate the query from a node inside the tree", func() {
+			q, err := ParseQuery("../ FuncDecl [ line() == 6 ] -/ *")
+
+			So(err, ShouldBeNil)
+
+			nodes, err := NewEvaluator(fset).EvalTree(q, tree, f.Decls[1].(*ast.FuncDecl).Body, nil)
+
+			So(err, ShouldBeNil)
+			So(typeNames(nodes), ShouldResemble, []string{"GenDecl"})
+
+			_, err = NewEvaluator(fset).EvalTree(q, tree, &ast.Ident{Name: "x"}, nil)
+
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "Ident node is not in the tree")
+		})
+	})
+
+	Convey("Given a decoded JSON document", t, func() {
+		var doc interface{}
+
+		So(json.Unmarshal([]byte(docSource), &doc), ShouldBeNil)
+
+		tree, err := NewDocTree(doc)
+
+		So(err, ShouldBeNil)
+
+		e := NewEvaluator(nil)
+
+		Convey("When evaluate the queries", func() {
+			var queries = map[string][]interface{}{
+				".// Object [ /:kind String [ @value == \"Deployment\" ] ] //:image String": {"nginx:latest", "envoy:1.0"},
+				"//:replicas Number [ @value > 2 ]":                                         {float64(3)},
+				"//:containers Object /:name *":                                             {"nginx", "sidecar"},
+				"//:containers Array / * [ @key == 1 ] / Null":                              {nil},
+				"// String [ @value =~ `^nginx` ] ..// Object [ /:kind * ]":                 {"null"},
+				"// * [ type() == \"Bool\" || @value == 0.5 ]":                              {false, 0.5},
+				"// * [ attrs(\"|\") == \"key|value\" && @key == \"app\" ]":                 {"web"},
+				"/ Object +/ *": {"\"spec\""},
+			}
+
+			for s, expected := range queries {
+				q, err := ParseQuery(s)
+
+				So(err, ShouldBeNil)
+
+				nodes, err := e.EvalTree(q, tree, tree.Root, nil)
+
+				So(err, ShouldBeNil)
+				So(docValues(nodes), ShouldResemble, expected)
+			}
+		})
+
+		Convey("When navigate the document tree", func() {
+			metadata := tree.Children(tree.Root)[2]
+
+			So(tree.Type(metadata), ShouldEqual, DocObject)
+			So(tree.Parent(metadata), ShouldEqual, tree.Root)
+			So(tree.Parent(tree.Root), ShouldBeNil)
+			So(tree.Field(tree.Root, metadata), ShouldEqual, "metadata")
+			So(tree.Pos(metadata), ShouldEqual, token.NoPos)
+		})
+
+		Convey("When evaluate the queries from a node inside the document", func() {
+			containers := tree.Root.children[3].(*DocNode).children[2].(*DocNode).children[0].(*DocNode).children[0]
+
+			So(containers.(*DocNode).Key, ShouldEqual, Str("containers"))
+
+			for s, expected := range map[string][]interface{}{
+				"..// Object [ /:kind * ] /:kind *":                             {"Deployment"},
+				"/ * [ @key == 0 ] /:image * [ line() == 0 && file() == \"\" ]": {"nginx:latest"},
+			} {
+				q, err := ParseQuery(s)
+
+				So(err, ShouldBeNil)
+
+				nodes, err := e.EvalTree(q, tree, containers, nil)
+
+				So(err, ShouldBeNil)
+				So(docValues(nodes), ShouldResemble, expected)
+			}
+		})
+	})
+
+	Convey("Given a decoded YAML document", t, func() {
+		doc := map[interface{}]interface{}{
+			"ports": []interface{}{80, 443},
+			1:       map[interface{}]interface{}{"enabled": true},
+		}
+
+		tree, err := NewDocTree(doc)
+
+		So(err, ShouldBeNil)
+
+		Convey("When evaluate the queries", func() {
+			q, err := ParseQuery("//:ports Number [ @value > 100 ], // * [ @key == \"1\" ] / Bool")
+
+			So(err, ShouldBeNil)
+
+			nodes, err := NewEvaluator(nil).EvalTree(q, tree, tree.Root, nil)
+
+			So(err, ShouldBeNil)
+			So(docValues(nodes), ShouldResemble, []interface{}{true, 443})
+		})
+
+		Convey("When decode the unsupported values", func() {
+			_, err := NewDocTree(map[string]interface{}{"f": func() {}})
+
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "unsupported document value of type func()")
+		})
+	})
+}
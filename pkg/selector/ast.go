@@ -3,13 +3,15 @@ package selector
 import (
 	"bytes"
 	"fmt"
+	"go/ast"
 	"regexp"
 	"strconv"
 	"strings"
 	"unicode"
 )
 
-type Query []Path
+// Query is the union of the paths or set expressions of paths.
+type Query []PathExpr
 
 func (q Query) String() string {
 	var pathes []string
@@ -21,6 +23,32 @@ func (q Query) String() string {
 	return strings.Join(pathes, ", ")
 }
 
+// PathExpr is a Path or a SetExpr.
+type PathExpr interface {
+	fmt.Stringer
+}
+
+// SetExpr is the intersection (`&`) or difference (`-`) of the nodes matched by the operands.
+type SetExpr struct {
+	Lhs PathExpr
+	Op  string
+	Rhs PathExpr
+}
+
+func (s *SetExpr) String() string {
+	lhs, rhs := s.Lhs.String(), s.Rhs.String()
+
+	if l, ok := s.Lhs.(*SetExpr); ok && l.Op == "-" && s.Op == "&" {
+		lhs = "(" + lhs + ")"
+	}
+
+	if r, ok := s.Rhs.(*SetExpr); ok && (s.Op == "&" || r.Op == "-") {
+		rhs = "(" + rhs + ")"
+	}
+
+	return fmt.Sprintf("%s %s %s", lhs, s.Op, rhs)
+}
+
 type Path []*Step
 
 func (p Path) String() string {
@@ -33,11 +61,28 @@ func (p Path) String() string {
 	return strings.Join(steps, " ")
 }
 
+// HasResult reports whether any step of the path is marked as result.
+func (p Path) HasResult() bool {
+	for _, step := range p {
+		if step.Result {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Step matches the nodes reached through the axis by the type name,
+// or the nodes not matched by the negated step, e.g. `not(FuncDecl [@method])`,
+// or the nodes matched by the named query from the context node, e.g. `$funcs [@method]`.
 type Step struct {
 	*Axis
 	Match  string
+	Not    *Step
+	Ref    *Ref
 	Result bool
 	Filter Expr
+	Slice  *Slice
 }
 
 func (s *Step) String() string {
@@ -51,9 +96,17 @@ func (s *Step) String() string {
 		}
 	}
 
-	buf.WriteString(s.Match)
+	if s.Not != nil {
+		buf.WriteString("not(" + s.Not.String() + ")")
+	} else if s.Ref != nil {
+		buf.WriteString(s.Ref.String())
+	} else if s.Match == "*" {
+		buf.WriteString(s.Match)
+	} else {
+		buf.WriteString(quoteIdent(s.Match))
+	}
 
-	if s.Result || s.Filter != nil {
+	if s.Result || s.Filter != nil || s.Slice != nil {
 		buf.WriteRune(' ')
 
 		if s.Result {
@@ -63,23 +116,92 @@ func (s *Step) String() string {
 		if s.Filter != nil {
 			buf.WriteString("[" + s.Filter.String() + "]")
 		}
+
+		if s.Slice != nil {
+			buf.WriteString(s.Slice.String())
+		}
 	}
 
 	return buf.String()
 }
 
+// Slice selects the nodes matched by the step for each context node by position in document order,
+// e.g. `[1]` for the first node, `[-1]` for the last node, `[2:5]` for the second to fifth nodes.
+//
+// The position counts from 1, and the negative position counts from the end.
+// The zero position is the open bound of range, e.g. `[2:]` or `[:-2]`.
+type Slice struct {
+	Start int  `json:"start,omitempty"`
+	End   int  `json:"end,omitempty"`
+	Range bool `json:"range,omitempty"`
+}
+
+func (s *Slice) String() string {
+	if !s.Range {
+		return fmt.Sprintf("[%d]", s.Start)
+	}
+
+	var start, end string
+
+	if s.Start != 0 {
+		start = strconv.Itoa(s.Start)
+	}
+
+	if s.End != 0 {
+		end = strconv.Itoa(s.End)
+	}
+
+	return "[" + start + ":" + end + "]"
+}
+
+// Select returns the nodes at the positions.
+func (s *Slice) Select(nodes []ast.Node) []ast.Node {
+	n := len(nodes)
+
+	pos := func(i, bound int) int {
+		switch {
+		case i < 0:
+			return n + i + 1
+		case i == 0:
+			return bound
+		default:
+			return i
+		}
+	}
+
+	start, end := pos(s.Start, 1), pos(s.End, n)
+
+	if !s.Range {
+		if s.Start == 0 {
+			return nil
+		}
+
+		end = start
+	}
+
+	if start < 1 {
+		start = 1
+	}
+
+	if end > n {
+		end = n
+	}
+
+	if start > end {
+		return nil
+	}
+
+	return nodes[start-1 : end]
+}
+
 type Axis struct {
-	Dir  string
-	Type string
+	Dir  string `json:"dir"`
+	Type string `json:"type,omitempty"`
 }
 
 func (a *Axis) String() string {
 	if len(a.Type) > 0 {
-		if !IsIdent(a.Type) {
-			return fmt.Sprintf("%s:%s", a.Dir, strconv.Quote(a.Type))
-		}
-
-		return fmt.Sprintf("%s:%s", a.Dir, a.Type)
+		return fmt.Sprintf("%s:%s", a.Dir, quoteIdent(a.Type))
 	}
 
 	return a.Dir
@@ -89,6 +211,52 @@ type Expr interface {
 	fmt.Stringer
 }
 
+// precedence returns the precedence level of the expression in grammar,
+// the operand is enclosed in parentheses when its level is lower than expected.
+//
+//	0  ?: ?:
+//	1  || && !
+//	2  & | << >> ~
+//	3  == != < <= > >= =~ !~
+//	4  + - * / % ^
+//	5  literal, function call, attribute, query parameter, subquery
+func precedence(expr Expr) int {
+	switch expr := expr.(type) {
+	case *Cond:
+		return 0
+
+	case *Unary:
+		if expr.Op == "!" {
+			return 1
+		}
+
+		return 2
+
+	case *Binary:
+		switch {
+		case expr.IsLogical():
+			return 1
+		case expr.IsBitwise():
+			return 2
+		case expr.IsArithmethical():
+			return 4
+		default:
+			return 3
+		}
+
+	default:
+		return 5
+	}
+}
+
+func operand(expr Expr, level int) string {
+	if precedence(expr) < level {
+		return "(" + expr.String() + ")"
+	}
+
+	return expr.String()
+}
+
 type Cond struct {
 	Cond Expr
 	Then Expr
@@ -98,15 +266,15 @@ type Cond struct {
 func (c *Cond) String() string {
 	buf := new(bytes.Buffer)
 
-	buf.WriteString(c.Cond.String() + " ")
+	buf.WriteString(operand(c.Cond, 1) + " ")
 
 	if c.Then != nil {
-		buf.WriteString("? " + c.Then.String() + " ")
+		buf.WriteString("? " + operand(c.Then, 1) + " ")
 	} else {
 		buf.WriteString("?")
 	}
 
-	buf.WriteString(": " + c.Else.String())
+	buf.WriteString(": " + operand(c.Else, 1))
 
 	return buf.String()
 }
@@ -117,7 +285,7 @@ type Unary struct {
 }
 
 func (u *Unary) String() string {
-	return fmt.Sprintf("%s %s", u.Op, u.Expr)
+	return fmt.Sprintf("%s %s", u.Op, operand(u.Expr, precedence(u)+1))
 }
 
 type Binary struct {
@@ -158,7 +326,14 @@ func (b *Binary) IsArithmethical() bool {
 }
 
 func (b *Binary) String() string {
-	return fmt.Sprintf("%s %s %s", b.Lhs, b.Op, b.Rhs)
+	level := precedence(b) + 1
+	lhs := operand(b.Lhs, level)
+
+	if _, ok := b.Lhs.(Path); ok && b.Op == "/" {
+		lhs = "(" + lhs + ")" // the division is parsed as the child axis of subquery
+	}
+
+	return fmt.Sprintf("%s %s %s", lhs, b.Op, operand(b.Rhs, level))
 }
 
 type FuncCall struct {
@@ -181,21 +356,30 @@ type WithAttr struct {
 }
 
 func (a *WithAttr) String() string {
-	return "@" + a.ID
+	return "@" + quoteIdent(a.ID)
 }
 
 type Regexp struct {
 	*regexp.Regexp
 }
 
+var reFlags = regexp.MustCompile("^\\(\\?([" + regexpFlags + "]+)\\)")
+
+// String returns the regex with the leading flags group as suffix, e.g. `foo`i
 func (r *Regexp) String() string {
-	return "`" + r.Regexp.String() + "`"
+	s := r.Regexp.String()
+
+	if m := reFlags.FindStringSubmatch(s); m != nil {
+		return "`" + s[len(m[0]):] + "`" + m[1]
+	}
+
+	return "`" + s + "`"
 }
 
 type Str string
 
 func (s Str) String() string {
-	return `"` + string(s) + `"`
+	return quote(string(s))
 }
 
 type Num int64
@@ -204,6 +388,18 @@ func (n Num) String() string {
 	return strconv.FormatInt(int64(n), 10)
 }
 
+type Float float64
+
+func (f Float) String() string {
+	s := strconv.FormatFloat(float64(f), 'g', -1, 64)
+
+	if !strings.ContainsAny(s, ".eEIN") {
+		s += ".0"
+	}
+
+	return s
+}
+
 type Keyword string
 
 func (k Keyword) String() string {
@@ -213,15 +409,40 @@ func (k Keyword) String() string {
 type QueryParam string
 
 func (p QueryParam) String() string {
-	return string(p)
+	return "{" + string(p) + "}"
 }
 
+// IsIdent reports whether the string is lexed as an identifier.
 func IsIdent(s string) bool {
-	for _, c := range s {
-		if unicode.IsControl(c) || unicode.IsSpace(c) {
+	for i, c := range s {
+		if !unicode.IsLetter(c) && c != '_' && (i == 0 || !unicode.IsDigit(c)) {
 			return false
 		}
 	}
 
-	return true
+	return len(s) > 0
+}
+
+// isKeyword reports whether the identifier is lexed as a keyword.
+func isKeyword(s string) bool {
+	switch s {
+	case "true", "false", "null", "let", "import":
+		return true
+	default:
+		return false
+	}
+}
+
+// quoteIdent quotes the name unless it is lexed as an identifier.
+func quoteIdent(s string) string {
+	if IsIdent(s) && !isKeyword(s) {
+		return s
+	}
+
+	return quote(s)
+}
+
+// quote quotes the string, the replacement character is escaped since it is skipped by the lexer.
+func quote(s string) string {
+	return strings.Replace(strconv.Quote(s), string(unicode.ReplacementChar), `\ufffd`, -1)
 }
@@ -0,0 +1,238 @@
+package selector
+
+import (
+	"go/ast"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+type attrGetter func(node ast.Node) Value
+
+// nodeAttrs is the attribute model, indexed by node type name and attribute name.
+//
+//	@name     Ident, FuncDecl, TypeSpec, Field, ValueSpec, ImportSpec,
+//	          SelectorExpr, LabeledStmt, BranchStmt, File
+//	@exported Ident, FuncDecl, TypeSpec, Field, ValueSpec
+//	@method   FuncDecl
+//	@value    BasicLit
+//	@kind     BasicLit
+//	@op       BinaryExpr, UnaryExpr
+//	@tok      AssignStmt, GenDecl, IncDecStmt, BranchStmt, RangeStmt
+//	@path     ImportSpec
+//	@tag      Field
+//	@text     Comment, CommentGroup
+var nodeAttrs = map[string]map[string]attrGetter{
+	"Ident": {
+		"name": func(n ast.Node) Value {
+			return Str(n.(*ast.Ident).Name)
+		},
+		"exported": func(n ast.Node) Value {
+			return Bool(n.(*ast.Ident).IsExported())
+		},
+	},
+	"FuncDecl": {
+		"name": func(n ast.Node) Value {
+			return Str(n.(*ast.FuncDecl).Name.Name)
+		},
+		"exported": func(n ast.Node) Value {
+			return Bool(n.(*ast.FuncDecl).Name.IsExported())
+		},
+		"method": func(n ast.Node) Value {
+			return Bool(n.(*ast.FuncDecl).Recv != nil)
+		},
+	},
+	"TypeSpec": {
+		"name": func(n ast.Node) Value {
+			return Str(n.(*ast.TypeSpec).Name.Name)
+		},
+		"exported": func(n ast.Node) Value {
+			return Bool(n.(*ast.TypeSpec).Name.IsExported())
+		},
+	},
+	"Field": {
+		"name": func(n ast.Node) Value {
+			return identNames(n.(*ast.Field).Names)
+		},
+		"exported": func(n ast.Node) Value {
+			return identsExported(n.(*ast.Field).Names)
+		},
+		"tag": func(n ast.Node) Value {
+			if tag := n.(*ast.Field).Tag; tag != nil {
+				return unquote(tag.Value)
+			}
+
+			return Null{}
+		},
+	},
+	"ValueSpec": {
+		"name": func(n ast.Node) Value {
+			return identNames(n.(*ast.ValueSpec).Names)
+		},
+		"exported": func(n ast.Node) Value {
+			return identsExported(n.(*ast.ValueSpec).Names)
+		},
+	},
+	"ImportSpec": {
+		"name": func(n ast.Node) Value {
+			if name := n.(*ast.ImportSpec).Name; name != nil {
+				return Str(name.Name)
+			}
+
+			return Null{}
+		},
+		"path": func(n ast.Node) Value {
+			return unquote(n.(*ast.ImportSpec).Path.Value)
+		},
+	},
+	"SelectorExpr": {
+		"name": func(n ast.Node) Value {
+			return Str(n.(*ast.SelectorExpr).Sel.Name)
+		},
+	},
+	"LabeledStmt": {
+		"name": func(n ast.Node) Value {
+			return Str(n.(*ast.LabeledStmt).Label.Name)
+		},
+	},
+	"BranchStmt": {
+		"name": func(n ast.Node) Value {
+			if label := n.(*ast.BranchStmt).Label; label != nil {
+				return Str(label.Name)
+			}
+
+			return Null{}
+		},
+		"tok": func(n ast.Node) Value {
+			return Str(n.(*ast.BranchStmt).Tok.String())
+		},
+	},
+	"File": {
+		"name": func(n ast.Node) Value {
+			return Str(n.(*ast.File).Name.Name)
+		},
+	},
+	"BasicLit": {
+		"value": func(n ast.Node) Value {
+			return literalValue(n.(*ast.BasicLit))
+		},
+		"kind": func(n ast.Node) Value {
+			return Str(n.(*ast.BasicLit).Kind.String())
+		},
+	},
+	"BinaryExpr": {
+		"op": func(n ast.Node) Value {
+			return Str(n.(*ast.BinaryExpr).Op.String())
+		},
+	},
+	"UnaryExpr": {
+		"op": func(n ast.Node) Value {
+			return Str(n.(*ast.UnaryExpr).Op.String())
+		},
+	},
+	"AssignStmt": {
+		"tok": func(n ast.Node) Value {
+			return Str(n.(*ast.AssignStmt).Tok.String())
+		},
+	},
+	"GenDecl": {
+		"tok": func(n ast.Node) Value {
+			return Str(n.(*ast.GenDecl).Tok.String())
+		},
+	},
+	"IncDecStmt": {
+		"tok": func(n ast.Node) Value {
+			return Str(n.(*ast.IncDecStmt).Tok.String())
+		},
+	},
+	"RangeStmt": {
+		"tok": func(n ast.Node) Value {
+			return Str(n.(*ast.RangeStmt).Tok.String())
+		},
+	},
+	"Comment": {
+		"text": func(n ast.Node) Value {
+			return Str(n.(*ast.Comment).Text)
+		},
+	},
+	"CommentGroup": {
+		"text": func(n ast.Node) Value {
+			return Str(n.(*ast.CommentGroup).Text())
+		},
+	},
+}
+
+// NodeAttr returns the named attribute of the node, or false if the node has no such attribute.
+func NodeAttr(node ast.Node, name string) (Value, bool) {
+	if getter, ok := nodeAttrs[typeName(node)][name]; ok {
+		return getter(node), true
+	}
+
+	return nil, false
+}
+
+// NodeAttrs returns all the attributes of the node.
+func NodeAttrs(node ast.Node) map[string]Value {
+	attrs := make(map[string]Value)
+
+	for name, getter := range nodeAttrs[typeName(node)] {
+		attrs[name] = getter(node)
+	}
+
+	return attrs
+}
+
+func identNames(idents []*ast.Ident) Value {
+	if len(idents) == 0 {
+		return Null{}
+	}
+
+	var names []string
+
+	for _, ident := range idents {
+		names = append(names, ident.Name)
+	}
+
+	return Str(strings.Join(names, ","))
+}
+
+func identsExported(idents []*ast.Ident) Value {
+	if len(idents) == 0 {
+		return Null{}
+	}
+
+	for _, ident := range idents {
+		if !ident.IsExported() {
+			return Bool(false)
+		}
+	}
+
+	return Bool(true)
+}
+
+func literalValue(lit *ast.BasicLit) Value {
+	switch lit.Kind {
+	case token.INT:
+		if n, err := strconv.ParseInt(lit.Value, 0, 64); err == nil {
+			return Num(n)
+		}
+
+	case token.FLOAT:
+		if f, err := strconv.ParseFloat(lit.Value, 64); err == nil {
+			return Float(f)
+		}
+
+	case token.STRING, token.CHAR:
+		return unquote(lit.Value)
+	}
+
+	return Str(lit.Value)
+}
+
+func unquote(s string) Value {
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		return Str(unquoted)
+	}
+
+	return Str(s)
+}
@@ -0,0 +1,120 @@
+package selector
+
+import (
+	"go/ast"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const attrSource = `
+package test
+
+import (
+	"fmt"
+	str "strings"
+)
+
+// Point is a point.
+type Point struct {
+	X, Y int ` + "`json:\"x\"`" + `
+	label string
+}
+
+func (p *Point) String() string {
+	return fmt.Sprintf("(%d, %d)", p.X, p.Y)
+}
+
+func TestPoint(t *testing.T) {
+	var n = 0x10
+	n += 'a'
+	n++
+
+	if !str.HasPrefix("foo", "f") && n > 1 {
+		return
+	}
+}
+`
+
+var attrQueries = map[string][]string{
+	"// FuncDecl [ @name =~ `^Test` ]":                  {"TestPoint"},
+	"// FuncDecl [ @exported ]":                         {"String", "TestPoint"},
+	"// FuncDecl [ @method ]":                           {"String"},
+	"// TypeSpec [ @name == \"Point\" ]":                {"Point"},
+	"// Field [ @exported ] /:Names Ident":              {"X", "Y"},
+	"// Field [ @tag == \"json:\\\"x\\\"\" ] /:Names *": {"X", "Y"},
+	"// Field [ ! @exported ] /:Names Ident":            {"label", "p", "t"},
+	"// Ident [ @name == \"fmt\" ]":                     {"fmt"},
+}
+
+func TestAttr(t *testing.T) {
+	Convey("Given a parsed file", t, func() {
+		_, f := parseSource(attrSource)
+
+		Convey("When evaluate query with name attribute", func() {
+			for q, expected := range attrQueries {
+				nodes, err := evalQuery(f, q)
+
+				So(err, ShouldBeNil)
+
+				var names []string
+
+				for _, node := range nodes {
+					v, _ := NodeAttr(node, "name")
+					names = append(names, string(v.(Str)))
+				}
+
+				So(names, ShouldResemble, expected)
+			}
+		})
+
+		Convey("When evaluate query with other attributes", func() {
+			nodes, err := evalQuery(f, "// ImportSpec [ @path == \"strings\" && @name == \"str\" ]")
+
+			So(err, ShouldBeNil)
+			So(nodes, ShouldHaveLength, 1)
+
+			nodes, err = evalQuery(f, "// BasicLit [ @kind == \"INT\" && @value == 16 ]")
+
+			So(err, ShouldBeNil)
+			So(nodes, ShouldHaveLength, 1)
+			So(nodes[0].(*ast.BasicLit).Value, ShouldEqual, "0x10")
+
+			nodes, err = evalQuery(f, "// BasicLit [ @kind == \"CHAR\" && @value == \"a\" ]")
+
+			So(err, ShouldBeNil)
+			So(nodes, ShouldHaveLength, 1)
+
+			nodes, err = evalQuery(f, "// BinaryExpr [ @op == \"&&\" ] / UnaryExpr [ @op == \"!\" ]")
+
+			So(err, ShouldBeNil)
+			So(nodes, ShouldHaveLength, 1)
+
+			nodes, err = evalQuery(f, "// AssignStmt [ @tok == \"+=\" ], // IncDecStmt [ @tok == \"++\" ], // GenDecl [ @tok == \"type\" ]")
+
+			So(err, ShouldBeNil)
+			So(typeNames(nodes), ShouldResemble, []string{"GenDecl", "AssignStmt", "IncDecStmt"})
+
+			nodes, err = evalQuery(f, "// CommentGroup [ @text == \"Point is a point.\\n\" ]")
+
+			So(err, ShouldBeNil)
+			So(nodes, ShouldHaveLength, 1)
+		})
+
+		Convey("When evaluate query with missing attribute", func() {
+			nodes, err := evalQuery(f, "// FuncDecl [ @tag == null && @nmae == null ]")
+
+			So(err, ShouldBeNil)
+			So(nodes, ShouldHaveLength, 2)
+		})
+
+		Convey("When list the node attributes", func() {
+			So(NodeAttrs(f.Decls[1]), ShouldResemble, map[string]Value{"tok": Str("type")})
+			So(NodeAttrs(f.Decls[2]), ShouldResemble, map[string]Value{
+				"name":     Str("String"),
+				"exported": Bool(true),
+				"method":   Bool(true),
+			})
+		})
+	})
+}
@@ -0,0 +1,157 @@
+package selector
+
+import (
+	"fmt"
+	"go/ast"
+)
+
+// axis returns the candidate nodes reachable from the node through the axis.
+//
+//	/    direct child nodes
+//	//   any descendant nodes
+//	./   current node plus direct child nodes
+//	.//  current node plus any descendant nodes
+//	-/   direct left sibling node
+//	-//  any left sibling nodes
+//	+/   direct right sibling node
+//	+//  any right sibling nodes
+//	~/   direct left and right sibling nodes
+//	~//  all left and right sibling nodes
+//	../  direct parent node
+//	..// any parent nodes
+//	<//  any preceding nodes, except the parent nodes
+//	>//  any following nodes, except the descendant nodes
+//
+// A step without axis is treated as `./`.
+//
+// The axis type names the field of the parent node through which a candidate
+// node is reached, e.g. `/:Body BlockStmt` or `//:Results *`. The fields held by
+// a FieldList are also reachable through the field which holds the list,
+// so `/:Params Field` matches the parameters of a FuncType.
+func (e *evaluation) axis(axis *Axis, node ast.Node) ([]ast.Node, error) {
+	if axis == nil {
+		return e.axisDir("./", node)
+	}
+
+	nodes, err := e.axisDir(axis.Dir, node)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return e.reachedBy(axis, nodes), nil
+}
+
+// reachedBy returns the nodes reached through the axis type.
+func (e *evaluation) reachedBy(axis *Axis, nodes []ast.Node) []ast.Node {
+	if len(axis.Type) == 0 {
+		return nodes
+	}
+
+	if axis.Dir == "/" {
+		var fields []ast.Node
+
+		for _, child := range nodes {
+			if _, ok := child.(*ast.FieldList); ok {
+				fields = append(fields, e.tree.children(child)...)
+			}
+		}
+
+		nodes = append(append([]ast.Node{}, nodes...), fields...)
+	}
+
+	var typed []ast.Node
+
+	for _, n := range nodes {
+		if e.tree.reachedBy(n, axis.Type) {
+			typed = append(typed, n)
+		}
+	}
+
+	return typed
+}
+
+func (e *evaluation) axisDir(dir string, node ast.Node) ([]ast.Node, error) {
+	t := e.tree
+
+	switch dir {
+	case "/":
+		return t.children(node), nil
+
+	case "//":
+		return t.descendants(node), nil
+
+	case "./":
+		return append([]ast.Node{node}, t.children(node)...), nil
+
+	case ".//":
+		return append([]ast.Node{node}, t.descendants(node)...), nil
+
+	case "-/":
+		left, _ := t.siblings(node)
+
+		if len(left) > 0 {
+			return left[len(left)-1:], nil
+		}
+
+		return nil, nil
+
+	case "-//":
+		left, _ := t.siblings(node)
+
+		return left, nil
+
+	case "+/":
+		_, right := t.siblings(node)
+
+		if len(right) > 0 {
+			return right[:1], nil
+		}
+
+		return nil, nil
+
+	case "+//":
+		_, right := t.siblings(node)
+
+		return right, nil
+
+	case "~/":
+		left, right := t.siblings(node)
+
+		var nodes []ast.Node
+
+		if len(left) > 0 {
+			nodes = append(nodes, left[len(left)-1])
+		}
+
+		if len(right) > 0 {
+			nodes = append(nodes, right[0])
+		}
+
+		return nodes, nil
+
+	case "~//":
+		left, right := t.siblings(node)
+
+		return append(append([]ast.Node{}, left...), right...), nil
+
+	case "../":
+		if parent := t.parent(node); parent != nil {
+			return []ast.Node{parent}, nil
+		}
+
+		return nil, nil
+
+	case "..//":
+		return t.ancestors(node), nil
+
+	case "<//":
+		return t.preceding(node), nil
+
+	case ">//":
+		return t.following(node), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported axis: %s", dir)
+	}
+}
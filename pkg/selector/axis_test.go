@@ -0,0 +1,90 @@
+package selector
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const axisSource = `
+package test
+
+func Foo(a, b int, c string) (int, error) {
+	if a > b {
+		println(a)
+		return a, nil
+	}
+
+	return b, nil
+}
+`
+
+var axisQueries = map[string][]string{
+	"/ FuncDecl":                            {"FuncDecl"},
+	"/ *":                                   {"Ident", "FuncDecl"},
+	"// Field":                              {"Field", "Field", "Field", "Field"},
+	"./ *":                                  {"File", "Ident", "FuncDecl"},
+	"// IfStmt .// *":                       {"IfStmt", "BinaryExpr", "Ident", "Ident", "BlockStmt", "ExprStmt", "CallExpr", "Ident", "Ident", "ReturnStmt", "Ident", "Ident"},
+	"// ReturnStmt -/ *":                    {"IfStmt", "ExprStmt"},
+	"// ReturnStmt -// *":                   {"IfStmt", "ExprStmt"},
+	"// FuncDecl / BlockStmt -// *":         {"Ident", "FuncType"},
+	"// IfStmt +/ *":                        {"ReturnStmt"},
+	"// FuncDecl / Ident +/ *":              {"FuncType"},
+	"// FuncDecl / Ident +// *":             {"FuncType", "BlockStmt"},
+	"// FuncType ~/ *":                      {"Ident", "BlockStmt"},
+	"// FuncDecl / Ident ~/ *":              {"FuncType"},
+	"// FuncDecl / Ident ~// *":             {"FuncType", "BlockStmt"},
+	"// IfStmt / BlockStmt / ExprStmt ~/ *": {"ReturnStmt"},
+	"// FuncType / FieldList ~// *":         {"FieldList", "FieldList"},
+	"// CallExpr ../ *":                     {"ExprStmt"},
+	"// CallExpr ../ ExprStmt":              {"ExprStmt"},
+	"../ *":                                 {},
+	"// CallExpr ..// *":                    {"File", "FuncDecl", "BlockStmt", "IfStmt", "BlockStmt", "ExprStmt"},
+	"// CallExpr ..// IfStmt":               {"IfStmt"},
+	"// ExprStmt <// ReturnStmt":            {},
+	"// ExprStmt <// BinaryExpr":            {"BinaryExpr"},
+	"// ExprStmt <// BlockStmt":             {},
+	"// ExprStmt >// ReturnStmt":            {"ReturnStmt", "ReturnStmt"},
+	"// ExprStmt >// CallExpr":              {},
+	"// FuncDecl /:Body *":                  {"BlockStmt"},
+	"// FuncDecl /:Type *":                  {"FuncType"},
+	"// FuncType /:Params *":                {"FieldList", "Field", "Field"},
+	"// FuncType /:Results Field":           {"Field", "Field"},
+	"// FuncDecl //:Results *":              {"FieldList", "Field", "Field", "Ident", "Ident", "Ident", "Ident"},
+	"// IfStmt /:Cond *":                    {"BinaryExpr"},
+	"// IfStmt /:Body * / *":                {"ExprStmt", "ReturnStmt"},
+	"// BinaryExpr /:X Ident":               {"Ident"},
+	"// Field /:Names *":                    {"Ident", "Ident", "Ident"},
+	"// Field /:Type Ident ../:List *":      {"Field", "Field", "Field", "Field"},
+	"// FuncDecl /:Recv *":                  {},
+	"// IfStmt ~//:Results *":               {},
+}
+
+func TestAxis(t *testing.T) {
+	Convey("Given a parsed file", t, func() {
+		_, f := parseSource(axisSource)
+
+		Convey("When evaluate query with axis", func() {
+			for q, expected := range axisQueries {
+				nodes, err := evalQuery(f, q)
+
+				So(err, ShouldBeNil)
+
+				if len(expected) == 0 {
+					So(nodes, ShouldBeEmpty)
+				} else {
+					So(typeNames(nodes), ShouldResemble, expected)
+				}
+			}
+		})
+
+		Convey("When evaluate query with unknown axis", func() {
+			q := Query{Path{&Step{Axis: &Axis{Dir: "^/"}, Match: "*"}}}
+
+			_, err := q.Eval(f)
+
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "unsupported axis: ^/")
+		})
+	})
+}
@@ -0,0 +1,801 @@
+package selector
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// PathBuilder builds a path step by step, or a set expression of paths, e.g.
+//
+//	q, err := selector.Descendant("FuncDecl").Where(selector.Attr("name").Matches(re)).Query()
+//
+// The built query is checked to be printed as a query which is parsed to an equal query,
+// so ParseQuery(q.String()) always returns a query equal to q.
+//
+// The builder is immutable, each method returns a new builder.
+type PathBuilder struct {
+	expr PathExpr
+	err  error
+}
+
+func newStep(dir, match string) *Step {
+	step := &Step{Match: match}
+
+	if len(dir) > 0 {
+		step.Axis = &Axis{Dir: dir}
+	}
+
+	return step
+}
+
+// Match starts a path with the step without axis, which matches the context node.
+func Match(match string) *PathBuilder { return new(PathBuilder).step(newStep("", match)) }
+
+// Named starts a path with the step of the named query, e.g. `$funcs`.
+func Named(name string) *PathBuilder { return new(PathBuilder).Named(name) }
+
+// Child starts a path with the `/` step.
+func Child(match string) *PathBuilder { return new(PathBuilder).Child(match) }
+
+// Descendant starts a path with the `//` step.
+func Descendant(match string) *PathBuilder { return new(PathBuilder).Descendant(match) }
+
+// SelfOrChild starts a path with the `./` step.
+func SelfOrChild(match string) *PathBuilder { return new(PathBuilder).SelfOrChild(match) }
+
+// SelfOrDescendant starts a path with the `.//` step.
+func SelfOrDescendant(match string) *PathBuilder { return new(PathBuilder).SelfOrDescendant(match) }
+
+// PrevSibling starts a path with the `-/` step.
+func PrevSibling(match string) *PathBuilder { return new(PathBuilder).PrevSibling(match) }
+
+// PrevSiblings starts a path with the `-//` step.
+func PrevSiblings(match string) *PathBuilder { return new(PathBuilder).PrevSiblings(match) }
+
+// NextSibling starts a path with the `+/` step.
+func NextSibling(match string) *PathBuilder { return new(PathBuilder).NextSibling(match) }
+
+// NextSiblings starts a path with the `+//` step.
+func NextSiblings(match string) *PathBuilder { return new(PathBuilder).NextSiblings(match) }
+
+// Sibling starts a path with the `~/` step.
+func Sibling(match string) *PathBuilder { return new(PathBuilder).Sibling(match) }
+
+// Siblings starts a path with the `~//` step.
+func Siblings(match string) *PathBuilder { return new(PathBuilder).Siblings(match) }
+
+// Parent starts a path with the `../` step.
+func Parent(match string) *PathBuilder { return new(PathBuilder).Parent(match) }
+
+// Ancestor starts a path with the `..//` step.
+func Ancestor(match string) *PathBuilder { return new(PathBuilder).Ancestor(match) }
+
+// Preceding starts a path with the `<//` step.
+func Preceding(match string) *PathBuilder { return new(PathBuilder).Preceding(match) }
+
+// Following starts a path with the `>//` step.
+func Following(match string) *PathBuilder { return new(PathBuilder).Following(match) }
+
+// Match appends the step without axis.
+func (b *PathBuilder) Match(match string) *PathBuilder { return b.step(newStep("", match)) }
+
+// Named appends the step of the named query, e.g. `$funcs`.
+func (b *PathBuilder) Named(name string) *PathBuilder {
+	return b.step(&Step{Ref: &Ref{Name: name}})
+}
+
+// Child appends the `/` step.
+func (b *PathBuilder) Child(match string) *PathBuilder { return b.step(newStep("/", match)) }
+
+// Descendant appends the `//` step.
+func (b *PathBuilder) Descendant(match string) *PathBuilder { return b.step(newStep("//", match)) }
+
+// SelfOrChild appends the `./` step.
+func (b *PathBuilder) SelfOrChild(match string) *PathBuilder { return b.step(newStep("./", match)) }
+
+// SelfOrDescendant appends the `.//` step.
+func (b *PathBuilder) SelfOrDescendant(match string) *PathBuilder {
+	return b.step(newStep(".//", match))
+}
+
+// PrevSibling appends the `-/` step.
+func (b *PathBuilder) PrevSibling(match string) *PathBuilder { return b.step(newStep("-/", match)) }
+
+// PrevSiblings appends the `-//` step.
+func (b *PathBuilder) PrevSiblings(match string) *PathBuilder { return b.step(newStep("-//", match)) }
+
+// NextSibling appends the `+/` step.
+func (b *PathBuilder) NextSibling(match string) *PathBuilder { return b.step(newStep("+/", match)) }
+
+// NextSiblings appends the `+//` step.
+func (b *PathBuilder) NextSiblings(match string) *PathBuilder { return b.step(newStep("+//", match)) }
+
+// Sibling appends the `~/` step.
+func (b *PathBuilder) Sibling(match string) *PathBuilder { return b.step(newStep("~/", match)) }
+
+// Siblings appends the `~//` step.
+func (b *PathBuilder) Siblings(match string) *PathBuilder { return b.step(newStep("~//", match)) }
+
+// Parent appends the `../` step.
+func (b *PathBuilder) Parent(match string) *PathBuilder { return b.step(newStep("../", match)) }
+
+// Ancestor appends the `..//` step.
+func (b *PathBuilder) Ancestor(match string) *PathBuilder { return b.step(newStep("..//", match)) }
+
+// Preceding appends the `<//` step.
+func (b *PathBuilder) Preceding(match string) *PathBuilder { return b.step(newStep("<//", match)) }
+
+// Following appends the `>//` step.
+func (b *PathBuilder) Following(match string) *PathBuilder { return b.step(newStep(">//", match)) }
+
+// Via sets the field of the parent node through which the last step is reached, e.g. `/:Body BlockStmt`.
+func (b *PathBuilder) Via(field string) *PathBuilder {
+	return b.last(func(step *Step) error {
+		if step.Axis == nil {
+			return errors.New("missing axis of the step")
+		}
+
+		step.Axis = &Axis{step.Axis.Dir, field}
+
+		return nil
+	})
+}
+
+// Where filters the nodes matched by the last step, e.g. `[ @name == "foo" ]`.
+//
+// The condition is an *ExprBuilder, an Expr, a *PathBuilder as subquery or a literal value.
+func (b *PathBuilder) Where(cond interface{}) *PathBuilder {
+	expr, err := exprOf(cond)
+
+	return b.last(func(step *Step) error {
+		switch {
+		case err != nil:
+			return err
+		case step.Filter != nil || step.Slice != nil:
+			return errors.New("duplicated predicate of the step")
+		}
+
+		if _, ok := expr.(Num); ok {
+			return fmt.Errorf("number filter %s is a positional predicate, use At instead", expr)
+		}
+
+		step.Filter = expr
+
+		return nil
+	})
+}
+
+// At selects the node matched by the last step at the position, e.g. `[1]` or `[-1]`.
+func (b *PathBuilder) At(pos int) *PathBuilder {
+	return b.slice(&Slice{Start: pos, End: pos})
+}
+
+// Slice selects the nodes matched by the last step in the positions, e.g. `[2:]` or `[2:-1]`.
+//
+// The zero position is the open bound of range.
+func (b *PathBuilder) Slice(start, end int) *PathBuilder {
+	return b.slice(&Slice{start, end, true})
+}
+
+func (b *PathBuilder) slice(slice *Slice) *PathBuilder {
+	return b.last(func(step *Step) error {
+		if step.Filter != nil || step.Slice != nil {
+			return errors.New("duplicated predicate of the step")
+		}
+
+		step.Slice = slice
+
+		return nil
+	})
+}
+
+// Mark marks the nodes matched by the last step as result, e.g. `//FuncDecl ! /Ident`.
+func (b *PathBuilder) Mark() *PathBuilder {
+	return b.last(func(step *Step) error {
+		step.Result = true
+
+		return nil
+	})
+}
+
+// Negate negates the node test and predicate of the last step, e.g. `// not(FuncDecl [@method])`.
+func (b *PathBuilder) Negate() *PathBuilder {
+	return b.last(func(step *Step) error {
+		if step.Ref != nil {
+			return fmt.Errorf("cannot negate the reference: %s", step.Ref)
+		}
+
+		if step.Result {
+			return errors.New("cannot negate the marked step")
+		}
+
+		inner := *step
+		inner.Axis = nil
+
+		*step = Step{Axis: step.Axis, Not: &inner}
+
+		return nil
+	})
+}
+
+// Intersect returns the set expression of nodes matched by both paths, e.g. `A & B`.
+func (b *PathBuilder) Intersect(other *PathBuilder) *PathBuilder { return b.set("&", other) }
+
+// Except returns the set expression of nodes matched by the path but not the other, e.g. `A - B`.
+func (b *PathBuilder) Except(other *PathBuilder) *PathBuilder { return b.set("-", other) }
+
+func (b *PathBuilder) set(op string, other *PathBuilder) *PathBuilder {
+	switch {
+	case b.err != nil:
+		return b
+	case other.err != nil:
+		return other
+	case b.expr == nil || other.expr == nil:
+		return &PathBuilder{err: errors.New("empty path")}
+	}
+
+	return &PathBuilder{expr: &SetExpr{b.expr, op, other.expr}}
+}
+
+// Query returns the query of the path, or the error if the path is invalid.
+func (b *PathBuilder) Query() (Query, error) {
+	return Union(b)
+}
+
+// String returns the query of the path, or the error message if the path is invalid.
+func (b *PathBuilder) String() string {
+	q, err := b.Query()
+
+	if err != nil {
+		return err.Error()
+	}
+
+	return q.String()
+}
+
+// Union returns the query of nodes matched by any of the paths, e.g. `A, B`.
+func Union(paths ...*PathBuilder) (Query, error) {
+	var q Query
+
+	for _, path := range paths {
+		if path.err != nil {
+			return nil, path.err
+		}
+
+		q = append(q, path.expr)
+	}
+
+	if err := CheckQuery(q); err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+func (b *PathBuilder) step(step *Step) *PathBuilder {
+	if b.err != nil {
+		return b
+	}
+
+	var path Path
+
+	switch expr := b.expr.(type) {
+	case nil:
+	case Path:
+		path = expr
+	default:
+		return &PathBuilder{err: fmt.Errorf("cannot append step to the set expression: %s", expr)}
+	}
+
+	return &PathBuilder{expr: append(path[:len(path):len(path)], step)}
+}
+
+// last returns the builder with a copy of the last step updated.
+func (b *PathBuilder) last(update func(step *Step) error) *PathBuilder {
+	if b.err != nil {
+		return b
+	}
+
+	path, ok := b.expr.(Path)
+
+	if !ok || len(path) == 0 {
+		return &PathBuilder{err: errors.New("missing step of the path")}
+	}
+
+	step := *path[len(path)-1]
+
+	if err := update(&step); err != nil {
+		return &PathBuilder{err: err}
+	}
+
+	return &PathBuilder{expr: append(path[:len(path)-1:len(path)-1], &step)}
+}
+
+// ExprBuilder builds the expression of filter, e.g. `@name =~ "^Test" && len(@params) == 1`.
+//
+// The operand of operator is an *ExprBuilder, an Expr, a *PathBuilder as subquery or a literal value.
+//
+// The builder is immutable, each method returns a new builder.
+type ExprBuilder struct {
+	expr Expr
+	err  error
+}
+
+// Attr returns the expression of attribute, e.g. `@name`.
+func Attr(name string) *ExprBuilder { return &ExprBuilder{expr: &WithAttr{name}} }
+
+// Param returns the expression of query parameter, e.g. `{name}`.
+func Param(name string) *ExprBuilder { return &ExprBuilder{expr: QueryParam(name)} }
+
+// Lit returns the expression of literal value.
+//
+//	nil                      null
+//	bool                     true, false
+//	string                   "string"
+//	int, int8 ... uint64     number
+//	float32, float64         float
+//	*regexp.Regexp           `regex`
+func Lit(v interface{}) *ExprBuilder {
+	expr, err := literal(v)
+
+	return &ExprBuilder{expr, err}
+}
+
+// Call returns the expression of function call, e.g. `len(@params)`.
+func Call(name string, args ...interface{}) *ExprBuilder {
+	call := &FuncCall{ID: name}
+
+	for _, arg := range args {
+		expr, err := exprOf(arg)
+
+		if err != nil {
+			return &ExprBuilder{err: err}
+		}
+
+		call.Args = append(call.Args, expr)
+	}
+
+	return &ExprBuilder{expr: call}
+}
+
+// Subquery returns the expression of the nodes matched by the path from the context node, e.g. `/:Body BlockStmt`.
+//
+// The steps of subquery must have axis unless they reference the named queries, e.g. `$funcs [ @method ]`.
+func Subquery(path *PathBuilder) *ExprBuilder {
+	expr, err := exprOf(path)
+
+	return &ExprBuilder{expr, err}
+}
+
+// If returns the conditional expression, e.g. `@exported ? 1 : 0`.
+func If(cond, then, otherwise interface{}) *ExprBuilder {
+	c, err := exprOf(cond)
+
+	if err != nil {
+		return &ExprBuilder{err: err}
+	}
+
+	return (&ExprBuilder{expr: c}).cond(then, otherwise)
+}
+
+// Else returns the expression or the other expression if it is false, e.g. `@name ?: "unknown"`.
+func (b *ExprBuilder) Else(otherwise interface{}) *ExprBuilder { return b.cond(nil, otherwise) }
+
+func (b *ExprBuilder) cond(then, otherwise interface{}) *ExprBuilder {
+	if b.err != nil {
+		return b
+	}
+
+	c := &Cond{Cond: b.expr}
+
+	if then != nil {
+		expr, err := exprOf(then)
+
+		if err != nil {
+			return &ExprBuilder{err: err}
+		}
+
+		c.Then = expr
+	}
+
+	expr, err := exprOf(otherwise)
+
+	if err != nil {
+		return &ExprBuilder{err: err}
+	}
+
+	c.Else = expr
+
+	return &ExprBuilder{expr: c}
+}
+
+// Not returns the logical negation of the expression, e.g. `! @exported`.
+func (b *ExprBuilder) Not() *ExprBuilder { return b.unary("!") }
+
+// Invert returns the bitwise complement of the expression, e.g. `~ @value`.
+func (b *ExprBuilder) Invert() *ExprBuilder { return b.unary("~") }
+
+func (b *ExprBuilder) And(rhs interface{}) *ExprBuilder        { return b.binary("&&", rhs) }
+func (b *ExprBuilder) Or(rhs interface{}) *ExprBuilder         { return b.binary("||", rhs) }
+func (b *ExprBuilder) BitAnd(rhs interface{}) *ExprBuilder     { return b.binary("&", rhs) }
+func (b *ExprBuilder) BitOr(rhs interface{}) *ExprBuilder      { return b.binary("|", rhs) }
+func (b *ExprBuilder) Shl(rhs interface{}) *ExprBuilder        { return b.binary("<<", rhs) }
+func (b *ExprBuilder) Shr(rhs interface{}) *ExprBuilder        { return b.binary(">>", rhs) }
+func (b *ExprBuilder) Eq(rhs interface{}) *ExprBuilder         { return b.binary("==", rhs) }
+func (b *ExprBuilder) Ne(rhs interface{}) *ExprBuilder         { return b.binary("!=", rhs) }
+func (b *ExprBuilder) Lt(rhs interface{}) *ExprBuilder         { return b.binary("<", rhs) }
+func (b *ExprBuilder) Le(rhs interface{}) *ExprBuilder         { return b.binary("<=", rhs) }
+func (b *ExprBuilder) Gt(rhs interface{}) *ExprBuilder         { return b.binary(">", rhs) }
+func (b *ExprBuilder) Ge(rhs interface{}) *ExprBuilder         { return b.binary(">=", rhs) }
+func (b *ExprBuilder) Matches(rhs interface{}) *ExprBuilder    { return b.binary("=~", rhs) }
+func (b *ExprBuilder) NotMatches(rhs interface{}) *ExprBuilder { return b.binary("!~", rhs) }
+func (b *ExprBuilder) Add(rhs interface{}) *ExprBuilder        { return b.binary("+", rhs) }
+func (b *ExprBuilder) Sub(rhs interface{}) *ExprBuilder        { return b.binary("-", rhs) }
+func (b *ExprBuilder) Mul(rhs interface{}) *ExprBuilder        { return b.binary("*", rhs) }
+func (b *ExprBuilder) Div(rhs interface{}) *ExprBuilder        { return b.binary("/", rhs) }
+func (b *ExprBuilder) Mod(rhs interface{}) *ExprBuilder        { return b.binary("%", rhs) }
+func (b *ExprBuilder) Pow(rhs interface{}) *ExprBuilder        { return b.binary("^", rhs) }
+
+func (b *ExprBuilder) unary(op string) *ExprBuilder {
+	if b.err != nil {
+		return b
+	}
+
+	return &ExprBuilder{expr: &Unary{op, b.expr}}
+}
+
+func (b *ExprBuilder) binary(op string, rhs interface{}) *ExprBuilder {
+	if b.err != nil {
+		return b
+	}
+
+	expr, err := exprOf(rhs)
+
+	if err != nil {
+		return &ExprBuilder{err: err}
+	}
+
+	return &ExprBuilder{expr: &Binary{b.expr, op, expr}}
+}
+
+// Expr returns the expression, or the error if the expression is invalid.
+func (b *ExprBuilder) Expr() (Expr, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	if err := checkExpr(b.expr); err != nil {
+		return nil, err
+	}
+
+	return b.expr, nil
+}
+
+// String returns the expression, or the error message if the expression is invalid.
+func (b *ExprBuilder) String() string {
+	expr, err := b.Expr()
+
+	if err != nil {
+		return err.Error()
+	}
+
+	return expr.String()
+}
+
+func exprOf(x interface{}) (Expr, error) {
+	switch x := x.(type) {
+	case *ExprBuilder:
+		return x.expr, x.err
+
+	case *PathBuilder:
+		if x.err != nil {
+			return nil, x.err
+		}
+
+		if path, ok := x.expr.(Path); ok {
+			return path, nil
+		}
+
+		return nil, fmt.Errorf("unexpected subquery: %s", x.expr)
+
+	case *regexp.Regexp:
+		return &Regexp{x}, nil
+
+	case Expr:
+		return x, nil
+
+	default:
+		return literal(x)
+	}
+}
+
+func literal(x interface{}) (Expr, error) {
+	switch x := x.(type) {
+	case nil:
+		return Keyword("null"), nil
+	case bool:
+		return Keyword(fmt.Sprint(x)), nil
+	case string:
+		return Str(x), nil
+	case *regexp.Regexp:
+		return &Regexp{x}, nil
+	}
+
+	v := reflect.ValueOf(x)
+
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return Num(v.Int()), nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("number overflow: %d", v.Uint())
+		}
+
+		return Num(v.Uint()), nil
+
+	case reflect.Float32, reflect.Float64:
+		return Float(v.Float()), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported literal: %v (%T)", x, x)
+	}
+}
+
+// CheckQuery checks whether the query is printed as a query which is parsed to an equal query.
+func CheckQuery(q Query) error {
+	if len(q) == 0 {
+		return errors.New("empty query")
+	}
+
+	for _, expr := range q {
+		if err := checkPathExpr(expr); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func checkPathExpr(expr PathExpr) error {
+	switch expr := expr.(type) {
+	case Path:
+		return checkPath(expr, false)
+
+	case *SetExpr:
+		if expr.Op != "&" && expr.Op != "-" {
+			return fmt.Errorf("unknown set operator: %s", expr.Op)
+		}
+
+		if err := checkPathExpr(expr.Lhs); err != nil {
+			return err
+		}
+
+		return checkPathExpr(expr.Rhs)
+
+	default:
+		return fmt.Errorf("unexpected path: %v", expr)
+	}
+}
+
+// checkPath checks the path, the steps of subquery must have axis unless they reference the named queries.
+func checkPath(path Path, subquery bool) error {
+	if len(path) == 0 {
+		return errors.New("empty path")
+	}
+
+	for _, step := range path {
+		if step == nil {
+			return errors.New("missing step")
+		}
+
+		if step.Axis != nil {
+			if err := checkAxis(step.Axis); err != nil {
+				return err
+			}
+
+			if step.Ref != nil {
+				return fmt.Errorf("unexpected axis of the reference: %s", step.Ref)
+			}
+		} else if subquery && step.Ref == nil {
+			return fmt.Errorf("missing axis of the subquery step: %s", step)
+		}
+
+		if step.Ref != nil {
+			if step.Not != nil || len(step.Match) > 0 {
+				return fmt.Errorf("unexpected node test of the reference: %s", step.Ref)
+			}
+
+			if !IsIdent(step.Ref.Name) {
+				return fmt.Errorf("invalid reference name: %q", step.Ref.Name)
+			}
+
+			if err := checkPredicate(step); err != nil {
+				return err
+			}
+		} else if err := checkNodeTest(step); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+var axisDirs = map[string]bool{
+	"/": true, "//": true, "./": true, ".//": true, "-/": true, "-//": true, "+/": true,
+	"+//": true, "~/": true, "~//": true, "../": true, "..//": true, "<//": true, ">//": true,
+}
+
+func checkAxis(axis *Axis) error {
+	if !axisDirs[axis.Dir] {
+		return fmt.Errorf("unknown axis: %q", axis.Dir)
+	}
+
+	return nil
+}
+
+// checkNodeTest checks the match or negated node test with the predicate.
+func checkNodeTest(step *Step) error {
+	switch {
+	case step.Not != nil:
+		if len(step.Match) > 0 {
+			return fmt.Errorf("unexpected match of the negated step: %s", step.Match)
+		}
+
+		inner := step.Not
+
+		if inner.Axis != nil || inner.Ref != nil || inner.Result {
+			return fmt.Errorf("invalid negated step: %s", inner)
+		}
+
+		if err := checkNodeTest(inner); err != nil {
+			return err
+		}
+
+	case len(step.Match) == 0:
+		return errors.New("empty match of the step")
+	}
+
+	return checkPredicate(step)
+}
+
+func checkPredicate(step *Step) error {
+	if step.Filter != nil && step.Slice != nil {
+		return fmt.Errorf("both filter and slice of the step: %s", step)
+	}
+
+	if slice := step.Slice; slice != nil {
+		if int64(slice.Start) == math.MinInt64 || int64(slice.End) == math.MinInt64 {
+			return fmt.Errorf("position overflow: %s", slice)
+		}
+
+		if !slice.Range && slice.Start != slice.End {
+			return fmt.Errorf("mismatched position: %d, %d", slice.Start, slice.End)
+		}
+	}
+
+	if step.Filter != nil {
+		if _, ok := step.Filter.(Num); ok {
+			return fmt.Errorf("number filter is a positional predicate: [%s]", step.Filter)
+		}
+
+		return checkExpr(step.Filter)
+	}
+
+	return nil
+}
+
+func checkExpr(expr Expr) error {
+	switch expr := expr.(type) {
+	case nil:
+		return errors.New("missing expression")
+
+	case *Cond:
+		if err := checkExpr(expr.Cond); err != nil {
+			return err
+		}
+
+		if expr.Then != nil {
+			if err := checkExpr(expr.Then); err != nil {
+				return err
+			}
+		}
+
+		return checkExpr(expr.Else)
+
+	case *Unary:
+		if expr.Op != "!" && expr.Op != "~" {
+			return fmt.Errorf("unknown unary operator: %s", expr.Op)
+		}
+
+		return checkExpr(expr.Expr)
+
+	case *Binary:
+		if !expr.IsLogical() && !expr.IsBitwise() && !expr.IsRelational() && !expr.IsArithmethical() &&
+			expr.Op != "=~" && expr.Op != "!~" {
+			return fmt.Errorf("unknown binary operator: %s", expr.Op)
+		}
+
+		if err := checkExpr(expr.Lhs); err != nil {
+			return err
+		}
+
+		return checkExpr(expr.Rhs)
+
+	case *FuncCall:
+		if !IsIdent(expr.ID) || isKeyword(expr.ID) || expr.ID == "not" {
+			return fmt.Errorf("invalid function name: %q", expr.ID)
+		}
+
+		if expr.Args != nil && len(expr.Args) == 0 {
+			return fmt.Errorf("empty arguments of function: %s", expr.ID)
+		}
+
+		for _, arg := range expr.Args {
+			if err := checkExpr(arg); err != nil {
+				return err
+			}
+		}
+
+		return nil
+
+	case *WithAttr:
+		if len(expr.ID) == 0 {
+			return errors.New("empty attribute name")
+		}
+
+		return nil
+
+	case QueryParam:
+		if !IsIdent(string(expr)) || isKeyword(string(expr)) {
+			return fmt.Errorf("invalid query parameter: %q", string(expr))
+		}
+
+		return nil
+
+	case Path:
+		return checkPath(expr, true)
+
+	case Str:
+		return nil
+
+	case Num:
+		if expr == math.MinInt64 {
+			return fmt.Errorf("number overflow: %d", expr)
+		}
+
+		return nil
+
+	case Float:
+		if math.IsNaN(float64(expr)) || math.IsInf(float64(expr), 0) {
+			return fmt.Errorf("invalid float: %s", expr)
+		}
+
+		return nil
+
+	case *Regexp:
+		s := expr.Regexp.String()
+
+		if strings.ContainsAny(s, "`\ufffd") || !utf8.ValidString(s) {
+			return fmt.Errorf("regex can't be quoted: %q", s)
+		}
+
+		return nil
+
+	case Keyword:
+		switch expr {
+		case "true", "false", "null":
+			return nil
+		}
+
+		return fmt.Errorf("unknown keyword: %s", string(expr))
+
+	default:
+		return fmt.Errorf("unexpected expression: %v (%T)", expr, expr)
+	}
+}
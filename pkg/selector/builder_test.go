@@ -0,0 +1,250 @@
+package selector
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"regexp"
+	"testing"
+	"testing/quick"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBuilder(t *testing.T) {
+	Convey("Given a query builder", t, func() {
+		Convey("When build the valid queries", func() {
+			var queries = map[string]*PathBuilder{
+				"//FuncDecl [@name =~ `^Test`]": Descendant("FuncDecl").Where(Attr("name").Matches(regexp.MustCompile("^Test"))),
+				"//FuncDecl [@name =~ `^test`i]": Descendant("FuncDecl").
+					Where(Attr("name").Matches(regexp.MustCompile("(?i)^test"))),
+				"/:Body BlockStmt /*":                     Child("BlockStmt").Via("Body").Child("*"),
+				"//CallExpr ![len(@args) > 1]":            Descendant("CallExpr").Mark().Where(Call("len", Attr("args")).Gt(1)),
+				"//not(FuncDecl [@method])":               Descendant("FuncDecl").Where(Attr("method")).Negate(),
+				"//Field [1]":                             Descendant("Field").At(1),
+				"//Field [2:-1]":                          Descendant("Field").Slice(2, -1),
+				"$funcs [@exported]":                      Named("funcs").Where(Attr("exported")),
+				"//A - //B & //C":                         Descendant("A").Except(Descendant("B").Intersect(Descendant("C"))),
+				"(//A - //B) & //C":                       Descendant("A").Except(Descendant("B")).Intersect(Descendant("C")),
+				"//\"true\" [@\"a b\" == \"x\\ny\"]":      Descendant("true").Where(Attr("a b").Eq("x\ny")),
+				"* [(1 + 2) * 3 == {n} ? true : null]":    Match("*").Where(If(Lit(1).Add(2).Mul(3).Eq(Param("n")), true, nil)),
+				"* [(! (@a && @b)) || (~ (1 | 2)) == -1]": Match("*").Where(Attr("a").And(Attr("b")).Not().Or(Lit(1).BitOr(2).Invert().Eq(-1))),
+				"* [(//A) / 2 ?: 1.5]":                    Match("*").Where(Subquery(Descendant("A")).Div(2).Else(1.5)),
+				"* [now()]":                               Match("*").Where(Call("now")),
+				"A [/A $a]":                               Match("A").Where(Child("A").Named("a")),
+				"* [count($a [@b]) > 1]":                  Match("*").Where(Call("count", Named("a").Where(Attr("b"))).Gt(1)),
+			}
+
+			for s, b := range queries {
+				q, err := b.Query()
+
+				So(err, ShouldBeNil)
+				So(q.String(), ShouldEqual, s)
+
+				parsed, err := ParseQuery(s)
+
+				So(err, ShouldBeNil)
+				So(parsed, ShouldResemble, q)
+			}
+		})
+
+		Convey("When build the invalid queries", func() {
+			var queries = map[string]*PathBuilder{
+				"empty match of the step":                                   Descendant(""),
+				"missing axis of the step":                                  Match("A").Via("Body"),
+				"duplicated predicate of the step":                          Descendant("A").At(1).Where(Attr("a")),
+				"cannot negate the reference: $a":                           Named("a").Negate(),
+				"invalid reference name: \"a b\"":                           Named("a b"),
+				"invalid function name: \"true\"":                           Match("A").Where(Call("true")),
+				"invalid query parameter: \"a-b\"":                          Match("A").Where(Param("a-b")),
+				"missing axis of the subquery step: A":                      Match("A").Where(Match("A")),
+				"invalid float: NaN":                                        Match("A").Where(Lit(math.NaN())),
+				"number overflow: -9223372036854775808":                     Match("A").Where(Lit(math.MinInt64).Eq(1)),
+				"regex can't be quoted: \"`\"":                              Match("A").Where(Lit(regexp.MustCompile("`"))),
+				"unsupported literal: [] ([]int)":                           Match("A").Where([]int{}),
+				"cannot append step to the set expression: A - B":           Match("A").Except(Match("B")).Child("C"),
+				"number filter 1 is a positional predicate, use At instead": Match("A").Where(1),
+			}
+
+			for msg, b := range queries {
+				_, err := b.Query()
+
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, msg)
+			}
+		})
+
+		Convey("When build the union of paths", func() {
+			q, err := Union(Descendant("A"), Child("B").Mark())
+
+			So(err, ShouldBeNil)
+			So(q.String(), ShouldEqual, "//A, /B !")
+
+			_, err = Union()
+
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "empty query")
+		})
+	})
+}
+
+// randomQuery generates the random query with the builder.
+type randomQuery struct {
+	Query
+}
+
+func (randomQuery) Generate(r *rand.Rand, size int) reflect.Value {
+	g := &generator{r, size}
+
+	for {
+		paths := make([]*PathBuilder, 1+r.Intn(3))
+
+		for i := range paths {
+			paths[i] = g.pathExpr(2)
+		}
+
+		if q, err := Union(paths...); err == nil {
+			return reflect.ValueOf(randomQuery{q})
+		}
+	}
+}
+
+type generator struct {
+	*rand.Rand
+	size int
+}
+
+var (
+	generatedNames = []string{"FuncDecl", "*", "Ident", "_x1", "ÄÖ", "true", "not", "a b", "1", "\"`\\", "\x00\ufffd\xff"}
+	generatedAxes  = []func(*PathBuilder, string) *PathBuilder{
+		(*PathBuilder).Match, (*PathBuilder).Child, (*PathBuilder).Descendant, (*PathBuilder).SelfOrChild,
+		(*PathBuilder).SelfOrDescendant, (*PathBuilder).PrevSibling, (*PathBuilder).PrevSiblings,
+		(*PathBuilder).NextSibling, (*PathBuilder).NextSiblings, (*PathBuilder).Sibling, (*PathBuilder).Siblings,
+		(*PathBuilder).Parent, (*PathBuilder).Ancestor, (*PathBuilder).Preceding, (*PathBuilder).Following,
+	}
+	generatedOps = []func(*ExprBuilder, interface{}) *ExprBuilder{
+		(*ExprBuilder).And, (*ExprBuilder).Or, (*ExprBuilder).BitAnd, (*ExprBuilder).BitOr,
+		(*ExprBuilder).Shl, (*ExprBuilder).Shr, (*ExprBuilder).Eq, (*ExprBuilder).Ne,
+		(*ExprBuilder).Lt, (*ExprBuilder).Le, (*ExprBuilder).Gt, (*ExprBuilder).Ge,
+		(*ExprBuilder).Matches, (*ExprBuilder).NotMatches, (*ExprBuilder).Add, (*ExprBuilder).Sub,
+		(*ExprBuilder).Mul, (*ExprBuilder).Div, (*ExprBuilder).Mod, (*ExprBuilder).Pow,
+	}
+)
+
+func (g *generator) name() string {
+	return generatedNames[g.Intn(len(generatedNames))]
+}
+
+func (g *generator) pathExpr(depth int) *PathBuilder {
+	switch n := g.Intn(6); {
+	case depth > 0 && n == 0:
+		return g.pathExpr(depth - 1).Intersect(g.pathExpr(depth - 1))
+	case depth > 0 && n == 1:
+		return g.pathExpr(depth - 1).Except(g.pathExpr(depth - 1))
+	default:
+		return g.path(depth, false)
+	}
+}
+
+func (g *generator) path(depth int, subquery bool) *PathBuilder {
+	b := new(PathBuilder)
+
+	for i := 0; i < 1+g.Intn(3); i++ {
+		if !subquery && g.Intn(8) == 0 {
+			b = b.Named("funcs")
+		} else if subquery {
+			b = generatedAxes[1+g.Intn(len(generatedAxes)-1)](b, g.name())
+		} else {
+			b = generatedAxes[g.Intn(len(generatedAxes))](b, g.name())
+		}
+
+		if g.Intn(3) == 0 {
+			b = b.Via(g.name())
+		}
+
+		switch g.Intn(4) {
+		case 0:
+			b = b.Where(g.expr(depth))
+		case 1:
+			b = b.At(g.Intn(7) - 3)
+		case 2:
+			b = b.Slice(g.Intn(7)-3, g.Intn(7)-3)
+		}
+
+		if g.Intn(4) == 0 {
+			b = b.Negate()
+		}
+
+		if g.Intn(4) == 0 {
+			b = b.Mark()
+		}
+	}
+
+	return b
+}
+
+func (g *generator) expr(depth int) *ExprBuilder {
+	if depth <= 0 {
+		return g.operand()
+	}
+
+	switch g.Intn(8) {
+	case 0:
+		return If(g.expr(depth-1), g.expr(depth-1), g.expr(depth-1))
+	case 1:
+		return g.expr(depth - 1).Else(g.expr(depth - 1))
+	case 2:
+		return g.expr(depth - 1).Not()
+	case 3:
+		return g.expr(depth - 1).Invert()
+	case 4:
+		var args []interface{}
+
+		for i := 0; i < g.Intn(3); i++ {
+			args = append(args, g.expr(depth-1))
+		}
+
+		return Call("f", args...)
+	case 5:
+		return Subquery(g.path(depth-1, true))
+	default:
+		return generatedOps[g.Intn(len(generatedOps))](g.expr(depth-1), g.expr(depth-1))
+	}
+}
+
+func (g *generator) operand() *ExprBuilder {
+	switch g.Intn(9) {
+	case 0:
+		return Attr(g.name())
+	case 1:
+		return Param("p")
+	case 2:
+		return Lit(g.name())
+	case 3:
+		return Lit(g.Int63() - g.Int63())
+	case 4:
+		return Lit(g.NormFloat64() * math.Pow(10, float64(g.Intn(60)-30)))
+	case 5:
+		return Lit(regexp.MustCompile([]string{"^a", "(?i)b$", "(?ms)c.*", "(?i-s)d", ""}[g.Intn(5)]))
+	case 6:
+		return Lit([]interface{}{true, false, nil}[g.Intn(3)])
+	case 7:
+		return Lit(float64(g.Intn(5) - 2))
+	default:
+		return Lit(g.Intn(5) - 2)
+	}
+}
+
+func TestBuilderRoundTrip(t *testing.T) {
+	Convey("Given the random queries built by the builder", t, func() {
+		roundTrip := func(q randomQuery) bool {
+			parsed, err := ParseQuery(q.String())
+
+			return err == nil && reflect.DeepEqual(parsed, q.Query)
+		}
+
+		Convey("Then the printed query should be parsed to an equal query", func() {
+			So(quick.Check(roundTrip, &quick.Config{MaxCount: 1000}), ShouldBeNil)
+		})
+	})
+}
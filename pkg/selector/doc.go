@@ -0,0 +1,262 @@
+package selector
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// The type names of document nodes.
+const (
+	DocObject = "Object"
+	DocArray  = "Array"
+	DocString = "String"
+	DocNumber = "Number"
+	DocBool   = "Bool"
+	DocNull   = "Null"
+)
+
+// DocNode is a node of the document decoded into generic values,
+// e.g. `map[string]interface{}` from encoding/json or `map[interface{}]interface{}` from YAML decoders,
+// the maps are objects, the slices and arrays are arrays, and the rest are scalars.
+type DocNode struct {
+	Type  string
+	Key   Value       // the key of object member as Str, or the index of array element as Num, or Null for the root
+	Value interface{} // the decoded value
+
+	parent   *DocNode
+	children []ast.Node
+}
+
+// Pos returns token.NoPos, the decoded documents have no source positions.
+func (n *DocNode) Pos() token.Pos {
+	return token.NoPos
+}
+
+// End returns token.NoPos, the decoded documents have no source positions.
+func (n *DocNode) End() token.Pos {
+	return token.NoPos
+}
+
+// DocTree is the tree of document, the members of objects are ordered by key.
+//
+// The members and elements are reached through the field of their keys, and have the attributes
+//
+//	@key    the key of object member, or the index of array element counting from 0
+//	@value  the value of string, number, bool or null
+//
+// e.g. `.// Object [ /:kind String [ @value == "Deployment" ] ] //:image String`
+//
+// The nodes have no source positions, so `line()` and `column()` return 0 and `file()` returns an empty string.
+// The documents are evaluated through EvalTree only, Validate checks the queries against the go/ast schema,
+// and the command line tools load GO sources only.
+type DocTree struct {
+	Root *DocNode
+}
+
+// NewDocTree returns the tree of the decoded document.
+func NewDocTree(doc interface{}) (*DocTree, error) {
+	root, err := newDocNode(Null{}, doc, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &DocTree{root}, nil
+}
+
+func newDocNode(key Value, v interface{}, parent *DocNode) (*DocNode, error) {
+	n := &DocNode{Key: key, Value: v, parent: parent}
+
+	addChild := func(key Value, v interface{}) error {
+		child, err := newDocNode(key, v, n)
+
+		if err != nil {
+			return err
+		}
+
+		n.children = append(n.children, child)
+
+		return nil
+	}
+
+	rv := reflect.ValueOf(v)
+
+	switch {
+	case rv.Kind() == reflect.Map:
+		n.Type = DocObject
+
+		keys := rv.MapKeys()
+		names := make([]string, len(keys))
+
+		for i, key := range keys {
+			names[i] = fmt.Sprint(key.Interface())
+		}
+
+		sort.Sort(byName{names, keys})
+
+		for i, key := range keys {
+			if err := addChild(Str(names[i]), rv.MapIndex(key).Interface()); err != nil {
+				return nil, err
+			}
+		}
+
+	case rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array:
+		n.Type = DocArray
+
+		for i := 0; i < rv.Len(); i++ {
+			if err := addChild(Num(i), rv.Index(i).Interface()); err != nil {
+				return nil, err
+			}
+		}
+
+	default:
+		value, err := docValue(v)
+
+		if err != nil {
+			return nil, err
+		}
+
+		switch value.(type) {
+		case Null:
+			n.Type = DocNull
+		case Str:
+			n.Type = DocString
+		case Bool:
+			n.Type = DocBool
+		default:
+			n.Type = DocNumber
+		}
+	}
+
+	return n, nil
+}
+
+// byName sorts the map keys by their names.
+type byName struct {
+	names []string
+	keys  []reflect.Value
+}
+
+func (s byName) Len() int           { return len(s.names) }
+func (s byName) Less(i, j int) bool { return s.names[i] < s.names[j] }
+func (s byName) Swap(i, j int) {
+	s.names[i], s.names[j] = s.names[j], s.names[i]
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+}
+
+// docValue returns the value of the scalar.
+func docValue(v interface{}) (Value, error) {
+	if n, ok := v.(json.Number); ok {
+		if i, err := strconv.ParseInt(string(n), 10, 64); err == nil {
+			return Num(i), nil
+		}
+
+		f, err := n.Float64()
+
+		if err != nil {
+			return nil, err
+		}
+
+		return Float(f), nil
+	}
+
+	if v == nil {
+		return Null{}, nil
+	}
+
+	rv := reflect.ValueOf(v)
+
+	switch rv.Kind() {
+	case reflect.String:
+		return Str(rv.String()), nil
+
+	case reflect.Bool:
+		return Bool(rv.Bool()), nil
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return Num(rv.Int()), nil
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if x := rv.Uint(); x <= math.MaxInt64 {
+			return Num(x), nil
+		}
+
+		return Float(rv.Uint()), nil
+
+	case reflect.Float32, reflect.Float64:
+		// the numbers decoded from JSON are floats
+		if x := rv.Float(); x == math.Trunc(x) && math.Abs(x) < 1<<53 {
+			return Num(x), nil
+		}
+
+		return Float(rv.Float()), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported document value of type %T", v)
+	}
+}
+
+func (t *DocTree) Children(node ast.Node) []ast.Node {
+	return node.(*DocNode).children
+}
+
+func (t *DocTree) Parent(node ast.Node) ast.Node {
+	if parent := node.(*DocNode).parent; parent != nil {
+		return parent
+	}
+
+	return nil
+}
+
+func (t *DocTree) Type(node ast.Node) string {
+	return node.(*DocNode).Type
+}
+
+func (t *DocTree) Attr(node ast.Node, name string) (Value, bool) {
+	n := node.(*DocNode)
+
+	switch name {
+	case "key":
+		return n.Key, true
+
+	case "value":
+		if n.Type == DocObject || n.Type == DocArray {
+			return nil, false
+		}
+
+		v, err := docValue(n.Value)
+
+		return v, err == nil
+	}
+
+	return nil, false
+}
+
+func (t *DocTree) AttrNames(node ast.Node) []string {
+	if n := node.(*DocNode); n.Type == DocObject || n.Type == DocArray {
+		return []string{"key"}
+	}
+
+	return []string{"key", "value"}
+}
+
+func (t *DocTree) Pos(node ast.Node) token.Pos {
+	return token.NoPos
+}
+
+// Field returns the key of object member, the array elements are reached through the field of array.
+func (t *DocTree) Field(parent, child ast.Node) string {
+	for n := child.(*DocNode); n != nil; n = n.parent {
+		if s, ok := n.Key.(Str); ok {
+			return string(s)
+		}
+	}
+
+	return ""
+}
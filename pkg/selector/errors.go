@@ -0,0 +1,165 @@
+package selector
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// SyntaxError is an error in the query at the offending token.
+type SyntaxError struct {
+	Query    string   // the query source
+	Offset   int      // the byte offset of the token, counting from 0
+	Line     int      // the line number, counting from 1
+	Column   int      // the column number in bytes, counting from 1
+	Token    string   // the offending token, or empty at the end of query
+	Expected []string // the tokens which are expected instead
+	Err      error    // the lexical error if any
+}
+
+func (e *SyntaxError) Error() string {
+	pos := fmt.Sprintf("%d:%d", e.Line, e.Column)
+
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", pos, e.Err)
+	}
+
+	token := "EOF"
+
+	if e.Token != "" {
+		token = fmt.Sprintf("%q", e.Token)
+	}
+
+	msg := fmt.Sprintf("%s: syntax error: unexpected %s", pos, token)
+
+	switch n := len(e.Expected); n {
+	case 0:
+	case 1:
+		msg += ", expecting " + e.Expected[0]
+	default:
+		msg += ", expecting " + strings.Join(e.Expected[:n-1], ", ") + " or " + e.Expected[n-1]
+	}
+
+	return msg
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
+
+// Caret returns the query line of the error, with the carets under the offending token.
+//
+//	// FuncDecl [ @name == ]
+//	                     ^
+func (e *SyntaxError) Caret() string {
+	if e.Offset > len(e.Query) {
+		return ""
+	}
+
+	start := strings.LastIndexByte(e.Query[:e.Offset], '\n') + 1
+	end := len(e.Query)
+
+	if i := strings.IndexByte(e.Query[e.Offset:], '\n'); i >= 0 {
+		end = e.Offset + i
+	}
+
+	var buf strings.Builder
+
+	buf.WriteString(e.Query[start:end])
+	buf.WriteByte('\n')
+
+	for _, c := range e.Query[start:e.Offset] {
+		if c == '\t' {
+			buf.WriteRune(c)
+		} else {
+			buf.WriteRune(' ')
+		}
+	}
+
+	buf.WriteString("^")
+
+	if n := len([]rune(strings.SplitN(e.Token, "\n", 2)[0])); n > 1 {
+		buf.WriteString(strings.Repeat("^", n-1))
+	}
+
+	return buf.String()
+}
+
+// tokenNames are the readable names of tokens.
+var tokenNames = map[int]string{
+	eof:      "EOF",
+	ID:       "identifier",
+	STR:      "string",
+	NUM:      "number",
+	FLOAT:    "float",
+	REGEXP:   "regexp",
+	AXIS:     "axis",
+	NOT:      "not",
+	REF:      "reference",
+	LET:      "let",
+	IMPORT:   "import",
+	TRUE:     "true",
+	FALSE:    "false",
+	NULL:     "null",
+	LSHIFT:   "'<<'",
+	RSHIFT:   "'>>'",
+	AND:      "'&&'",
+	OR:       "'||'",
+	EQ:       "'=='",
+	NE:       "'!='",
+	LTE:      "'<='",
+	GTE:      "'>='",
+	MATCH:    "'=~'",
+	NONMATCH: "'!~'",
+	ELSE_OR:  "'?:'",
+}
+
+// expectedTokens are the tokens to try when finding the expected tokens, in the reported order.
+var expectedTokens []int
+
+func init() {
+	expectedTokens = []int{ID, STR, NUM, FLOAT, REGEXP, TRUE, FALSE, NULL, AXIS, NOT, REF, LET, IMPORT}
+
+	for _, c := range "[](){}:@.,;=!+-*/^%<>~&|?" {
+		tokenNames[int(c)] = fmt.Sprintf("'%c'", c)
+		expectedTokens = append(expectedTokens, int(c))
+	}
+
+	expectedTokens = append(expectedTokens,
+		LSHIFT, RSHIFT, AND, OR, EQ, NE, LTE, GTE, MATCH, NONMATCH, ELSE_OR, eof)
+}
+
+// LibraryError is the error of library file, which keeps the syntax errors of file for SyntaxErrors.
+type LibraryError struct {
+	Filename string
+	Err      error
+}
+
+func (e *LibraryError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Filename, e.Err)
+}
+
+func (e *LibraryError) Unwrap() error {
+	return e.Err
+}
+
+// SyntaxErrors returns the syntax errors reported by Parse, or by Load in the library file.
+func SyntaxErrors(err error) []*SyntaxError {
+	var errs []*SyntaxError
+
+	switch err := err.(type) {
+	case *SyntaxError:
+		errs = append(errs, err)
+
+	case *LibraryError:
+		errs = SyntaxErrors(err.Err)
+
+	case *multierror.Error:
+		for _, e := range err.Errors {
+			errs = append(errs, SyntaxErrors(e)...)
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,620 @@
+package selector
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"math"
+	"regexp"
+)
+
+// Eval evaluates the query against the syntax tree rooted at root,
+// and returns the matched nodes in document order.
+func (q Query) Eval(root ast.Node) ([]ast.Node, error) {
+	return NewEvaluator(nil).Eval(q, root)
+}
+
+// EvalWithParams evaluates the query with the bound query parameters.
+func (q Query) EvalWithParams(root ast.Node, params map[string]interface{}) ([]ast.Node, error) {
+	return NewEvaluator(nil).EvalWithParams(q, root, params)
+}
+
+// EvalTuples evaluates the query, and returns the nodes matched at the marked steps of each path match.
+func (q Query) EvalTuples(root ast.Node) ([]Tuple, error) {
+	return NewEvaluator(nil).EvalTuples(q, root, nil)
+}
+
+// Evaluator evaluates the queries, the optional file set is used to resolve the node positions.
+type Evaluator struct {
+	Fset *token.FileSet
+
+	funcs  map[string]Func
+	noPlan bool // scans the axes without the lookups of index
+}
+
+func NewEvaluator(fset *token.FileSet) *Evaluator {
+	return &Evaluator{Fset: fset}
+}
+
+func (e *Evaluator) Eval(q Query, root ast.Node) ([]ast.Node, error) {
+	return e.EvalWithParams(q, root, nil)
+}
+
+func (e *Evaluator) EvalWithParams(q Query, root ast.Node, params map[string]interface{}) ([]ast.Node, error) {
+	ev, err := e.prepare(q, root, params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ev.evalQuery(q, root)
+}
+
+func (e *Evaluator) EvalTuples(q Query, root ast.Node, params map[string]interface{}) ([]Tuple, error) {
+	ev, err := e.prepare(q, root, params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ev.evalQueryTuples(q, root)
+}
+
+func (e *Evaluator) prepare(q Query, root ast.Node, params map[string]interface{}) (*evaluation, error) {
+	bound, err := bindParams(q, params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ev := e.newEvaluation(root)
+	ev.params = bound
+
+	return ev, nil
+}
+
+type evaluation struct {
+	*Evaluator
+
+	tree   *tree
+	params map[string]Value
+	trace  *Explanation
+	ctx    context.Context // stops the evaluation if done
+}
+
+func (e *Evaluator) newEvaluation(root ast.Node) *evaluation {
+	return &evaluation{Evaluator: e, tree: NewIndex(root).get()}
+}
+
+func (e *evaluation) evalQuery(q Query, node ast.Node) ([]ast.Node, error) {
+	var nodes []ast.Node
+
+	for _, expr := range q {
+		matched, err := e.evalPathExpr(expr, node)
+
+		if err != nil {
+			return nil, err
+		}
+
+		nodes = append(nodes, matched...)
+	}
+
+	return e.tree.sort(nodes), nil
+}
+
+func (e *evaluation) evalPathExpr(expr PathExpr, node ast.Node) ([]ast.Node, error) {
+	switch expr := expr.(type) {
+	case Path:
+		return e.evalPath(expr, []ast.Node{node})
+
+	case *SetExpr:
+		lhs, err := e.evalPathExpr(expr.Lhs, node)
+
+		if err != nil {
+			return nil, err
+		}
+
+		rhs, err := e.evalPathExpr(expr.Rhs, node)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return setOp(expr.Op, lhs, rhs)
+
+	default:
+		return nil, fmt.Errorf("unexpected path expression: %s", expr)
+	}
+}
+
+// evalPath returns the nodes matched at the last step, or at the marked steps if any.
+func (e *evaluation) evalPath(path Path, nodes []ast.Node) ([]ast.Node, error) {
+	if path.HasResult() {
+		var tuples []Tuple
+
+		for _, node := range nodes {
+			matched, err := e.evalPathTuples(path, node)
+
+			if err != nil {
+				return nil, err
+			}
+
+			tuples = append(tuples, matched...)
+		}
+
+		var matched []ast.Node
+
+		for _, tuple := range tuples {
+			matched = append(matched, tuple...)
+		}
+
+		return e.tree.sort(matched), nil
+	}
+
+	for _, step := range path {
+		var matched []ast.Node
+
+		for _, node := range nodes {
+			found, err := e.evalStep(step, node)
+
+			if err != nil {
+				return nil, err
+			}
+
+			matched = append(matched, found...)
+		}
+
+		if nodes = e.tree.sort(matched); len(nodes) == 0 {
+			break
+		}
+	}
+
+	return nodes, nil
+}
+
+func (e *evaluation) evalStep(step *Step, node ast.Node) ([]ast.Node, error) {
+	if e.ctx != nil {
+		if err := e.ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	candidates, indexed, err := e.candidates(step, node)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if e.trace != nil {
+		return e.traceStep(step, candidates, indexed)
+	}
+
+	var matched []ast.Node
+
+	for _, candidate := range candidates {
+		ok, err := e.accept(step, candidate)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			matched = append(matched, candidate)
+		}
+	}
+
+	if step.Slice != nil {
+		matched = step.Slice.Select(e.tree.sort(matched))
+	}
+
+	return matched, nil
+}
+
+// traceStep selects the candidates like evalStep, and records the nodes survived in each stage.
+func (e *evaluation) traceStep(step *Step, candidates []ast.Node, indexed bool) ([]ast.Node, error) {
+	trace := e.trace.step(step)
+	trace.Indexed = indexed
+	trace.Contexts++
+	trace.Candidates += len(candidates)
+
+	var matched []ast.Node
+
+	for _, candidate := range candidates {
+		ok, err := e.test(step, candidate)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			continue
+		}
+
+		trace.Matched++
+
+		if ok, err = e.filter(step, candidate); err != nil {
+			return nil, err
+		}
+
+		if ok {
+			matched = append(matched, candidate)
+		} else if trace.Rejected == nil {
+			if trace.Rejected, err = e.untraced().reject(step.Filter, candidate); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	trace.Filtered += len(matched)
+
+	if step.Slice != nil {
+		matched = step.Slice.Select(e.tree.sort(matched))
+	}
+
+	trace.Selected += len(matched)
+
+	return matched, nil
+}
+
+// untraced returns the evaluation which doesn't record the traces of steps.
+func (e *evaluation) untraced() *evaluation {
+	untraced := *e
+	untraced.trace = nil
+
+	return &untraced
+}
+
+// accept reports whether the node is matched by the step and its filter.
+func (e *evaluation) accept(step *Step, node ast.Node) (bool, error) {
+	ok, err := e.test(step, node)
+
+	if err != nil || !ok {
+		return false, err
+	}
+
+	return e.filter(step, node)
+}
+
+// test reports whether the node is matched by the node test of step.
+func (e *evaluation) test(step *Step, node ast.Node) (bool, error) {
+	if step.Not != nil {
+		ok, err := e.accept(step.Not, node)
+
+		return !ok, err
+	}
+
+	return step.matches(node, e.tree.typeOf), nil
+}
+
+// filter reports whether the node is accepted by the filter of step.
+func (e *evaluation) filter(step *Step, node ast.Node) (bool, error) {
+	if step.Filter != nil {
+		v, err := e.evalExpr(step.Filter, node)
+
+		if err != nil {
+			return false, err
+		}
+
+		return isTrue(v), nil
+	}
+
+	return true, nil
+}
+
+// Matches reports whether the node type name matches the step,
+// the negated step with filter or the reference step matches any node.
+func (s *Step) Matches(node ast.Node) bool {
+	return s.matches(node, typeName)
+}
+
+func (s *Step) matches(node ast.Node, typeOf func(ast.Node) string) bool {
+	if s.Ref != nil {
+		return true
+	}
+
+	if s.Not != nil {
+		return s.Not.Filter != nil || !s.Not.matches(node, typeOf)
+	}
+
+	return s.Match == "*" || s.Match == typeOf(node)
+}
+
+func (e *evaluation) evalExpr(expr Expr, node ast.Node) (Value, error) {
+	switch expr := expr.(type) {
+	case *Cond:
+		return e.evalCond(expr, node)
+
+	case *Unary:
+		return e.evalUnary(expr, node)
+
+	case *Binary:
+		return e.evalBinary(expr, node)
+
+	case *FuncCall:
+		return e.evalFuncCall(expr, node)
+
+	case Path:
+		nodes, err := e.evalPath(expr, []ast.Node{node})
+
+		if err != nil {
+			return nil, err
+		}
+
+		return NodeSet(nodes), nil
+
+	case *WithAttr:
+		if v, ok := e.tree.attr(node, expr.ID); ok {
+			return v, nil
+		}
+
+		return Null{}, nil
+
+	case QueryParam:
+		if v, ok := e.params[string(expr)]; ok {
+			return v, nil
+		}
+
+		return nil, fmt.Errorf("unbound query parameter: %s", expr)
+
+	case Keyword:
+		switch expr {
+		case "true":
+			return Bool(true), nil
+		case "false":
+			return Bool(false), nil
+		default:
+			return Null{}, nil
+		}
+
+	case Value:
+		return expr, nil
+
+	default:
+		return nil, fmt.Errorf("unexpected expression: %s", expr)
+	}
+}
+
+func (e *evaluation) evalCond(c *Cond, node ast.Node) (Value, error) {
+	cond, err := e.evalExpr(c.Cond, node)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if isTrue(cond) {
+		if c.Then == nil {
+			return cond, nil
+		}
+
+		return e.evalExpr(c.Then, node)
+	}
+
+	return e.evalExpr(c.Else, node)
+}
+
+func (e *evaluation) evalUnary(u *Unary, node ast.Node) (Value, error) {
+	v, err := e.evalExpr(u.Expr, node)
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch u.Op {
+	case "!":
+		return Bool(!isTrue(v)), nil
+
+	case "~":
+		n, err := toNum(v)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return ^n, nil
+
+	default:
+		return nil, fmt.Errorf("unexpected unary operator: %s", u.Op)
+	}
+}
+
+func (e *evaluation) evalBinary(b *Binary, node ast.Node) (Value, error) {
+	lhs, err := e.evalExpr(b.Lhs, node)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if b.IsLogical() {
+		if (b.Op == "&&") != isTrue(lhs) {
+			return Bool(isTrue(lhs)), nil
+		}
+
+		rhs, err := e.evalExpr(b.Rhs, node)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return Bool(isTrue(rhs)), nil
+	}
+
+	rhs, err := e.evalExpr(b.Rhs, node)
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case b.Op == "=~" || b.Op == "!~":
+		var re *Regexp
+
+		switch rhs := rhs.(type) {
+		case *Regexp:
+			re = rhs
+
+		case Str:
+			r, err := regexp.Compile(string(rhs))
+
+			if err != nil {
+				return nil, err
+			}
+
+			re = &Regexp{r}
+
+		default:
+			return nil, fmt.Errorf("expected regexp for %s operator, got %s", b.Op, rhs.TypeName())
+		}
+
+		return Bool(re.MatchString(toStr(lhs)) == (b.Op == "=~")), nil
+
+	case b.Op == "==":
+		return Bool(equals(lhs, rhs)), nil
+
+	case b.Op == "!=":
+		return Bool(!equals(lhs, rhs)), nil
+
+	case b.IsRelational():
+		n, err := compare(lhs, rhs)
+
+		if err != nil {
+			// the values which can't be coerced to numbers are not ordered, as they are not equal
+			return Bool(false), nil
+		}
+
+		switch b.Op {
+		case "<":
+			return Bool(n < 0), nil
+		case "<=":
+			return Bool(n <= 0), nil
+		case ">":
+			return Bool(n > 0), nil
+		default:
+			return Bool(n >= 0), nil
+		}
+
+	case b.Op == "+" && (isStr(lhs) || isStr(rhs)):
+		return Str(toStr(lhs) + toStr(rhs)), nil
+
+	case (b.Op == "&" || b.Op == "-") && isNodeSet(lhs) && isNodeSet(rhs):
+		return setOp(b.Op, lhs.(NodeSet), rhs.(NodeSet))
+
+	case b.IsBitwise():
+		l, err := toNum(lhs)
+
+		if err != nil {
+			return nil, err
+		}
+
+		r, err := toNum(rhs)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return arith(b.Op, l, r)
+
+	case b.IsArithmethical():
+		l, err := toNumber(lhs)
+
+		if err != nil {
+			return nil, err
+		}
+
+		r, err := toNumber(rhs)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if l, ok := l.(Num); ok {
+			if r, ok := r.(Num); ok {
+				return arith(b.Op, l, r)
+			}
+		}
+
+		return floatArith(b.Op, toFloat(l), toFloat(r))
+
+	default:
+		return nil, fmt.Errorf("unexpected binary operator: %s", b.Op)
+	}
+}
+
+func arith(op string, l, r Num) (Value, error) {
+	switch op {
+	case "&":
+		return l & r, nil
+	case "|":
+		return l | r, nil
+	case "<<", ">>":
+		if r < 0 {
+			return nil, fmt.Errorf("negative shift count: %d %s %d", l, op, r)
+		}
+
+		if op == "<<" {
+			return l << uint64(r), nil
+		}
+
+		return l >> uint64(r), nil
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/", "%":
+		if r == 0 {
+			return nil, fmt.Errorf("division by zero: %d %s %d", l, op, r)
+		}
+
+		if op == "/" {
+			return l / r, nil
+		}
+
+		return l % r, nil
+	case "^":
+		if r < 0 {
+			return nil, fmt.Errorf("negative exponent: %d ^ %d", l, r)
+		}
+
+		// exponentiation by squaring, which wraps around on overflow like the other operators
+		n := Num(1)
+
+		for ; r > 0; r >>= 1 {
+			if r&1 == 1 {
+				n *= l
+			}
+
+			l *= l
+		}
+
+		return n, nil
+	default:
+		return nil, fmt.Errorf("unexpected arithmetical operator: %s", op)
+	}
+}
+
+func floatArith(op string, l, r Float) (Value, error) {
+	switch op {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/", "%":
+		if r == 0 {
+			return nil, fmt.Errorf("division by zero: %s %s %s", l, op, r)
+		}
+
+		if op == "/" {
+			return l / r, nil
+		}
+
+		return Float(math.Mod(float64(l), float64(r))), nil
+	case "^":
+		return Float(math.Pow(float64(l), float64(r))), nil
+	default:
+		return nil, fmt.Errorf("unexpected arithmetical operator: %s", op)
+	}
+}
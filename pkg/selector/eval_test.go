@@ -0,0 +1,236 @@
+package selector
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const evalSource = `
+package test
+
+import "fmt"
+
+func Foo(a, b int) int {
+	return a + b
+}
+
+func Bar() {
+	fmt.Println(Foo(1, 2))
+}
+`
+
+func parseSource(src string) (*token.FileSet, *ast.File) {
+	fset := token.NewFileSet()
+
+	f, err := parser.ParseFile(fset, "test.go", src, parser.ParseComments)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return fset, f
+}
+
+func evalQuery(root ast.Node, q string) ([]ast.Node, error) {
+	parsed, err := ParseQuery(q)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return parsed.Eval(root)
+}
+
+func typeNames(nodes []ast.Node) (names []string) {
+	for _, node := range nodes {
+		names = append(names, typeName(node))
+	}
+
+	return
+}
+
+func TestEval(t *testing.T) {
+	Convey("Given a parsed file", t, func() {
+		_, f := parseSource(evalSource)
+
+		Convey("When match the direct children", func() {
+			nodes, err := evalQuery(f, "/ FuncDecl")
+
+			So(err, ShouldBeNil)
+			So(nodes, ShouldHaveLength, 2)
+			So(nodes[0].(*ast.FuncDecl).Name.Name, ShouldEqual, "Foo")
+			So(nodes[1].(*ast.FuncDecl).Name.Name, ShouldEqual, "Bar")
+		})
+
+		Convey("When match the descendants", func() {
+			nodes, err := evalQuery(f, "// CallExpr")
+
+			So(err, ShouldBeNil)
+			So(nodes, ShouldHaveLength, 2)
+			So(nodes[0].(*ast.CallExpr).Fun, ShouldHaveSameTypeAs, &ast.SelectorExpr{})
+			So(nodes[1].(*ast.CallExpr).Fun.(*ast.Ident).Name, ShouldEqual, "Foo")
+		})
+
+		Convey("When match without axis", func() {
+			nodes, err := evalQuery(f, "File")
+
+			So(err, ShouldBeNil)
+			So(nodes, ShouldResemble, []ast.Node{f})
+
+			nodes, err = evalQuery(f, "GenDecl")
+
+			So(err, ShouldBeNil)
+			So(typeNames(nodes), ShouldResemble, []string{"GenDecl"})
+		})
+
+		Convey("When match with multi steps", func() {
+			nodes, err := evalQuery(f, "// FuncDecl // BasicLit")
+
+			So(err, ShouldBeNil)
+			So(nodes, ShouldHaveLength, 2)
+			So(nodes[0].(*ast.BasicLit).Value, ShouldEqual, "1")
+			So(nodes[1].(*ast.BasicLit).Value, ShouldEqual, "2")
+		})
+
+		Convey("When compare the values which can't be coerced to numbers", func() {
+			nodes, err := evalQuery(f, "// BasicLit [ @value > 1.5 ]")
+
+			So(err, ShouldBeNil)
+			So(nodes, ShouldHaveLength, 1)
+			So(nodes[0].(*ast.BasicLit).Value, ShouldEqual, "2")
+		})
+
+		Convey("When match with multi pathes", func() {
+			nodes, err := evalQuery(f, "// BinaryExpr, // ImportSpec, // ReturnStmt")
+
+			So(err, ShouldBeNil)
+			So(typeNames(nodes), ShouldResemble, []string{"ImportSpec", "ReturnStmt", "BinaryExpr"})
+		})
+
+		Convey("When match with wildcard", func() {
+			nodes, err := evalQuery(f, "// ReturnStmt / *")
+
+			So(err, ShouldBeNil)
+			So(typeNames(nodes), ShouldResemble, []string{"BinaryExpr"})
+		})
+
+		Convey("When match with filter", func() {
+			nodes, err := evalQuery(f, "// FuncDecl [ (1 + (2 * 3)) == 7 && true ]")
+
+			So(err, ShouldBeNil)
+			So(nodes, ShouldHaveLength, 2)
+
+			nodes, err = evalQuery(f, "// FuncDecl [ \"a\" + \"b\" != \"ab\" ]")
+
+			So(err, ShouldBeNil)
+			So(nodes, ShouldBeEmpty)
+		})
+
+		Convey("When match with unsupported expression", func() {
+			_, err := evalQuery(f, "// FuncDecl [ foo() ]")
+
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "unknown function: foo()")
+		})
+	})
+}
+
+func TestEvalExpr(t *testing.T) {
+	Convey("Given an evaluator", t, func() {
+		_, f := parseSource(evalSource)
+
+		e := NewEvaluator(nil).newEvaluation(f)
+
+		Convey("When evaluate expressions", func() {
+			var exprs = map[string]Value{
+				"1 + 2":                   Num(3),
+				"7 - 2":                   Num(5),
+				"3 * 4":                   Num(12),
+				"7 / 2":                   Num(3),
+				"7 % 2":                   Num(1),
+				"2 ^ 10":                  Num(1024),
+				"3 ^ 5":                   Num(243),
+				"(0 - 2) ^ 3":             Num(-8),
+				"1 ^ 9223372036854775807": Num(1),
+				"2 ^ 9223372036854775807": Num(0),
+				"6 & 3":                   Num(2),
+				"6 | 3":                   Num(7),
+				"1 << 4":                  Num(16),
+				"16 >> 2":                 Num(4),
+				"1 < 2":                   Bool(true),
+				"2 <= 2":                  Bool(true),
+				"1 > 2":                   Bool(false),
+				"2 >= 3":                  Bool(false),
+				"\"a\" < \"b\"":           Bool(true),
+				"1 == \"1\"":              Bool(true),
+				"null == false":           Bool(false),
+				"null == null":            Bool(true),
+				"true && 0":               Bool(false),
+				"0 || \"a\"":              Bool(true),
+				"! 0":                     Bool(true),
+				"~ 0":                     Num(-1),
+				"0 ? 1 : 2":               Num(2),
+				"3 ?: 4":                  Num(3),
+				"\"ab\" =~ `^a`":          Bool(true),
+				"\"ab\" !~ `^a`":          Bool(false),
+				"\"ab\" =~ \"b$\"":        Bool(true),
+				"\"a\" + 1":               Str("a1"),
+				"(1 + 2) * 3":             Num(9),
+				"\"10\" > 9":              Bool(true),
+				"1.5 + 1":                 Float(2.5),
+				"7 / 2.0":                 Float(3.5),
+				"7.5 % 2":                 Float(1.5),
+				"2 ^ 0.5 > 1.41":          Bool(true),
+				"1.0 == 1":                Bool(true),
+				"\"2.5\" * 2":             Float(5),
+				"-1 < 0":                  Bool(true),
+				"0x10 + 0b1":              Num(17),
+				"2.0 << 1":                Num(4),
+				"'a' == \"a\"":            Bool(true),
+				"\"ABC\" =~ `b`i":         Bool(true),
+				"\"a\" > 1.5":             Bool(false),
+				"\"a\" <= 1":              Bool(false),
+				"1 >= \"a\"":              Bool(false),
+			}
+
+			for s, expected := range exprs {
+				q, err := ParseQuery("* [" + s + "]")
+
+				So(err, ShouldBeNil)
+
+				v, err := e.evalExpr(q[0].(Path)[0].Filter, f)
+
+				So(err, ShouldBeNil)
+				So(v, ShouldResemble, expected)
+			}
+		})
+
+		Convey("When evaluate invalid expressions", func() {
+			var exprs = map[string]string{
+				"1 / 0":     "division by zero: 1 / 0",
+				"2 ^ (0-1)": "negative exponent: 2 ^ -1",
+				"\"a\" * 2": "cannot convert \"a\" to number",
+				"1 =~ 2":    "expected regexp for =~ operator, got number",
+				"1.5 | 1":   "cannot convert 1.5 to integer",
+				"1.5 / 0":   "division by zero: 1.5 / 0.0",
+				"1 << -1":   "negative shift count: 1 << -1",
+				"8 >> -1":   "negative shift count: 8 >> -1",
+			}
+
+			for s, expected := range exprs {
+				q, err := ParseQuery("* [" + s + "]")
+
+				So(err, ShouldBeNil)
+
+				_, err = e.evalExpr(q[0].(Path)[0].Filter, f)
+
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, expected)
+			}
+		})
+	})
+}
@@ -0,0 +1,263 @@
+package selector
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"strings"
+)
+
+// Explanation records how the nodes flow through the steps of an evaluated query.
+type Explanation struct {
+	Query Query
+	Nodes []ast.Node // the matched nodes
+	Steps map[*Step]*StepTrace
+
+	evaluator *Evaluator
+}
+
+// StepTrace records the nodes evaluated by a step, summed over all its context nodes.
+type StepTrace struct {
+	Contexts   int  // the context nodes the step is evaluated from
+	Candidates int  // the candidate nodes produced by the axis, reference or index lookup
+	Matched    int  // the candidates matched by the node test
+	Filtered   int  // the matched nodes accepted by the filter
+	Selected   int  // the accepted nodes selected by the slice
+	Indexed    bool // the candidates are looked up in the index of node type
+	Rejected   *Rejection
+}
+
+// Rejection is the sample node rejected by the filter of step.
+type Rejection struct {
+	Node     ast.Node
+	Expr     Expr // the sub-expression of filter which rejects the node
+	Value    Value
+	Operands []Operand // the values of non-literal operands of the sub-expression
+}
+
+// Operand is the value of an operand evaluated on the rejected node.
+type Operand struct {
+	Expr  Expr
+	Value Value
+}
+
+// Explain evaluates the query like Eval, and records how the nodes flow through each step.
+func (q Query) Explain(root ast.Node) (*Explanation, error) {
+	return NewEvaluator(nil).Explain(q, root, nil)
+}
+
+// Explain evaluates the query with the bound query parameters, and records how the nodes flow through each step.
+func (e *Evaluator) Explain(q Query, root ast.Node, params map[string]interface{}) (*Explanation, error) {
+	ev, err := e.prepare(q, root, params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ev.trace = &Explanation{Query: q, Steps: make(map[*Step]*StepTrace), evaluator: e}
+
+	if ev.trace.Nodes, err = ev.evalQuery(q, root); err != nil {
+		return nil, err
+	}
+
+	return ev.trace, nil
+}
+
+func (x *Explanation) step(step *Step) *StepTrace {
+	trace, ok := x.Steps[step]
+
+	if !ok {
+		trace = new(StepTrace)
+		x.Steps[step] = trace
+	}
+
+	return trace
+}
+
+// reject finds the sub-expression of filter which rejects the node.
+func (e *evaluation) reject(expr Expr, node ast.Node) (*Rejection, error) {
+	v, err := e.evalExpr(expr, node)
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch expr := expr.(type) {
+	case *Binary:
+		if expr.Op == "&&" {
+			lhs, err := e.evalExpr(expr.Lhs, node)
+
+			if err != nil {
+				return nil, err
+			}
+
+			if !isTrue(lhs) {
+				return e.reject(expr.Lhs, node)
+			}
+
+			return e.reject(expr.Rhs, node)
+		}
+
+		return e.rejection(node, expr, v, expr.Lhs, expr.Rhs)
+
+	case *Unary:
+		return e.rejection(node, expr, v, expr.Expr)
+
+	case *Cond:
+		c, err := e.evalExpr(expr.Cond, node)
+
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case !isTrue(c):
+			return e.reject(expr.Else, node)
+		case expr.Then != nil:
+			return e.reject(expr.Then, node)
+		}
+	}
+
+	return e.rejection(node, expr, v)
+}
+
+func (e *evaluation) rejection(node ast.Node, expr Expr, v Value, operands ...Expr) (*Rejection, error) {
+	r := &Rejection{Node: node, Expr: expr, Value: v}
+
+	for _, operand := range operands {
+		switch operand.(type) {
+		case Str, Num, Float, *Regexp, Keyword:
+			continue
+		}
+
+		v, err := e.evalExpr(operand, node)
+
+		if err != nil {
+			return nil, err
+		}
+
+		r.Operands = append(r.Operands, Operand{operand, v})
+	}
+
+	return r, nil
+}
+
+// String returns the query with the traces of steps as an indented tree, e.g.
+//
+//	query: //FuncDecl [@name =~ `^Test`] /:Body BlockStmt
+//	result: 0 node(s)
+//	path: //FuncDecl [@name =~ `^Test`] /:Body BlockStmt
+//	  step: //FuncDecl [@name =~ `^Test`]
+//	    contexts: 1, candidates: 2 (indexed), matched: 2, filtered: 0, selected: 0
+//	    rejected: FuncDecl at test.go:5:1 by @name =~ `^Test` = false, @name = "Foo"
+//	  step: /:Body BlockStmt
+//	    not evaluated
+func (x *Explanation) String() string {
+	buf := new(bytes.Buffer)
+
+	fmt.Fprintf(buf, "query: %s\n", x.Query)
+	fmt.Fprintf(buf, "result: %d node(s)\n", len(x.Nodes))
+
+	for _, expr := range x.Query {
+		x.writePathExpr(buf, expr, 0)
+	}
+
+	return buf.String()
+}
+
+func (x *Explanation) writePathExpr(buf *bytes.Buffer, expr PathExpr, depth int) {
+	indent := strings.Repeat("  ", depth)
+
+	switch expr := expr.(type) {
+	case Path:
+		fmt.Fprintf(buf, "%spath: %s\n", indent, expr)
+
+		for _, step := range expr {
+			x.writeStep(buf, step, depth+1)
+		}
+
+	case *SetExpr:
+		fmt.Fprintf(buf, "%sset: %s\n", indent, expr)
+
+		x.writePathExpr(buf, expr.Lhs, depth+1)
+		x.writePathExpr(buf, expr.Rhs, depth+1)
+	}
+}
+
+func (x *Explanation) writeStep(buf *bytes.Buffer, step *Step, depth int) {
+	indent := strings.Repeat("  ", depth)
+
+	fmt.Fprintf(buf, "%sstep: %s\n", indent, step)
+
+	trace, ok := x.Steps[step]
+
+	if !ok || trace.Contexts == 0 {
+		fmt.Fprintf(buf, "%s  not evaluated\n", indent)
+
+		return
+	}
+
+	var indexed string
+
+	if trace.Indexed {
+		indexed = " (indexed)"
+	}
+
+	fmt.Fprintf(buf, "%s  contexts: %d, candidates: %d%s, matched: %d, filtered: %d, selected: %d\n",
+		indent, trace.Contexts, trace.Candidates, indexed, trace.Matched, trace.Filtered, trace.Selected)
+
+	if r := trace.Rejected; r != nil {
+		fmt.Fprintf(buf, "%s  rejected: %s by %s = %s", indent, x.nodeString(r.Node), r.Expr, r.Value)
+
+		for _, operand := range r.Operands {
+			fmt.Fprintf(buf, ", %s = %s", operand.Expr, operand.Value)
+		}
+
+		buf.WriteString("\n")
+	}
+
+	if step.Ref != nil {
+		for _, expr := range step.Ref.Query {
+			x.writePathExpr(buf, expr, depth+1)
+		}
+	}
+
+	for s := step; s != nil; s = s.Not {
+		for _, path := range subqueries(s.Filter) {
+			x.writePathExpr(buf, path, depth+1)
+		}
+	}
+}
+
+// subqueries returns the subqueries of the expression, except the ones nested in the subqueries.
+func subqueries(expr Expr) (paths []Path) {
+	switch expr := expr.(type) {
+	case *Cond:
+		paths = append(subqueries(expr.Cond), subqueries(expr.Then)...)
+		paths = append(paths, subqueries(expr.Else)...)
+
+	case *Unary:
+		paths = subqueries(expr.Expr)
+
+	case *Binary:
+		paths = append(subqueries(expr.Lhs), subqueries(expr.Rhs)...)
+
+	case *FuncCall:
+		for _, arg := range expr.Args {
+			paths = append(paths, subqueries(arg)...)
+		}
+
+	case Path:
+		paths = []Path{expr}
+	}
+
+	return
+}
+
+func (x *Explanation) nodeString(node ast.Node) string {
+	if x.evaluator.Fset != nil {
+		return fmt.Sprintf("%s at %s", typeName(node), x.evaluator.Fset.Position(node.Pos()))
+	}
+
+	return fmt.Sprintf("%s at %d", typeName(node), node.Pos())
+}
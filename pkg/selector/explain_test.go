@@ -0,0 +1,97 @@
+package selector
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestExplain(t *testing.T) {
+	Convey("Given a parsed file", t, func() {
+		fset, f := parseSource(evalSource)
+
+		e := NewEvaluator(fset)
+
+		Convey("When explain a query without match", func() {
+			q, err := ParseQuery("// FuncDecl [ @exported && @name =~ `^Test` ] /:Body BlockStmt")
+
+			So(err, ShouldBeNil)
+
+			x, err := e.Explain(q, f, nil)
+
+			So(err, ShouldBeNil)
+			So(x.Nodes, ShouldBeEmpty)
+
+			trace := x.Steps[q[0].(Path)[0]]
+
+			So(trace, ShouldResemble, &StepTrace{
+				Contexts:   1,
+				Candidates: 2,
+				Matched:    2,
+				Indexed:    true,
+				Rejected: &Rejection{
+					Node:     f.Decls[1],
+					Expr:     q[0].(Path)[0].Filter.(*Binary).Rhs,
+					Value:    Bool(false),
+					Operands: []Operand{{&WithAttr{"name"}, Str("Foo")}},
+				},
+			})
+
+			So(x.String(), ShouldEqual, "query: //FuncDecl [@exported && @name =~ `^Test`] /:Body BlockStmt\n"+
+				"result: 0 node(s)\n"+
+				"path: //FuncDecl [@exported && @name =~ `^Test`] /:Body BlockStmt\n"+
+				"  step: //FuncDecl [@exported && @name =~ `^Test`]\n"+
+				"    contexts: 1, candidates: 2 (indexed), matched: 2, filtered: 0, selected: 0\n"+
+				"    rejected: FuncDecl at test.go:6:1 by @name =~ `^Test` = false, @name = \"Foo\"\n"+
+				"  step: /:Body BlockStmt\n"+
+				"    not evaluated\n")
+		})
+
+		Convey("When explain a query with subqueries and set operators", func() {
+			q, err := ParseQuery("// FuncDecl [ // ReturnStmt ] - $funcs")
+
+			So(err, ShouldBeNil)
+
+			lib := NewLibrary()
+
+			So(lib.Define("funcs", Query{Path{&Step{Axis: &Axis{Dir: "//"}, Match: "FuncDecl"}}}), ShouldBeNil)
+			So(lib.Resolve(q), ShouldBeNil)
+
+			x, err := e.Explain(q, f, nil)
+
+			So(err, ShouldBeNil)
+			So(x.Nodes, ShouldBeEmpty)
+			So(x.String(), ShouldEqual, "query: //FuncDecl [//ReturnStmt] - $funcs\n"+
+				"result: 0 node(s)\n"+
+				"set: //FuncDecl [//ReturnStmt] - $funcs\n"+
+				"  path: //FuncDecl [//ReturnStmt]\n"+
+				"    step: //FuncDecl [//ReturnStmt]\n"+
+				"      contexts: 1, candidates: 2 (indexed), matched: 2, filtered: 1, selected: 1\n"+
+				"      rejected: FuncDecl at test.go:10:1 by //ReturnStmt = []\n"+
+				"      path: //ReturnStmt\n"+
+				"        step: //ReturnStmt\n"+
+				"          contexts: 2, candidates: 1 (indexed), matched: 1, filtered: 1, selected: 1\n"+
+				"  path: $funcs\n"+
+				"    step: $funcs\n"+
+				"      contexts: 1, candidates: 2, matched: 2, filtered: 2, selected: 2\n"+
+				"      path: //FuncDecl\n"+
+				"        step: //FuncDecl\n"+
+				"          contexts: 1, candidates: 2 (indexed), matched: 2, filtered: 2, selected: 2\n")
+		})
+
+		Convey("When explain a query with the same result as evaluation", func() {
+			q, err := ParseQuery("// CallExpr ! / BasicLit")
+
+			So(err, ShouldBeNil)
+
+			nodes, err := e.Eval(q, f)
+
+			So(err, ShouldBeNil)
+
+			x, err := e.Explain(q, f, nil)
+
+			So(err, ShouldBeNil)
+			So(x.Nodes, ShouldResemble, nodes)
+		})
+	})
+}
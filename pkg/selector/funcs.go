@@ -0,0 +1,426 @@
+package selector
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// EvalContext is the context of the node which is being filtered.
+type EvalContext struct {
+	Node ast.Node
+	Fset *token.FileSet
+
+	tree *tree
+}
+
+func (ctx *EvalContext) Root() ast.Node {
+	return ctx.tree.root
+}
+
+func (ctx *EvalContext) Parent() ast.Node {
+	return ctx.tree.parent(ctx.Node)
+}
+
+func (ctx *EvalContext) Children() []ast.Node {
+	return ctx.tree.children(ctx.Node)
+}
+
+func (ctx *EvalContext) Depth() int {
+	return ctx.tree.depth(ctx.Node)
+}
+
+// Pos returns the position of the node among its sibling nodes, counting from 1.
+func (ctx *EvalContext) Pos() int {
+	left, _ := ctx.tree.siblings(ctx.Node)
+
+	return len(left) + 1
+}
+
+// Position returns the position of the node resolved by the file set,
+// or the zero position if the node has no position, e.g. the nodes of decoded documents.
+func (ctx *EvalContext) Position() (token.Position, error) {
+	pos := ctx.tree.pos(ctx.Node)
+
+	if !pos.IsValid() {
+		return token.Position{}, nil
+	}
+
+	if ctx.Fset == nil {
+		return token.Position{}, fmt.Errorf("no file set to resolve the position")
+	}
+
+	return ctx.Fset.Position(pos), nil
+}
+
+type Func func(ctx *EvalContext, args ...Value) (Value, error)
+
+// RegisterFunc registers the function to the evaluator, which could override the built-in function.
+func (e *Evaluator) RegisterFunc(name string, fn Func) {
+	if e.funcs == nil {
+		e.funcs = make(map[string]Func)
+	}
+
+	e.funcs[name] = fn
+}
+
+func (e *Evaluator) lookupFunc(name string) (*funcDef, bool) {
+	if fn, ok := e.funcs[name]; ok {
+		return &funcDef{0, -1, fn}, true
+	}
+
+	def, ok := builtins[name]
+
+	return def, ok
+}
+
+type funcDef struct {
+	minArgs int
+	maxArgs int
+	fn      Func
+}
+
+// checkArgs checks the number of arguments.
+func (def *funcDef) checkArgs(name string, n int) error {
+	if n < def.minArgs || (def.maxArgs >= 0 && n > def.maxArgs) {
+		var expected string
+
+		switch {
+		case def.minArgs == def.maxArgs:
+			expected = fmt.Sprintf("%d", def.minArgs)
+		case def.maxArgs < 0:
+			expected = fmt.Sprintf("at least %d", def.minArgs)
+		default:
+			expected = fmt.Sprintf("%d to %d", def.minArgs, def.maxArgs)
+		}
+
+		return fmt.Errorf("%s() expects %s argument(s), got %d", name, expected, n)
+	}
+
+	return nil
+}
+
+func (def *funcDef) call(name string, ctx *EvalContext, args []Value) (Value, error) {
+	if err := def.checkArgs(name, len(args)); err != nil {
+		return nil, err
+	}
+
+	v, err := def.fn(ctx, args...)
+
+	if err != nil {
+		return nil, fmt.Errorf("%s(): %v", name, err)
+	}
+
+	return v, nil
+}
+
+var builtins = map[string]*funcDef{
+	"depth":    {0, 0, builtinDepth},
+	"pos":      {0, 0, builtinPos},
+	"nth":      {1, 1, builtinNth},
+	"first":    {0, 0, builtinFirst},
+	"last":     {0, 0, builtinLast},
+	"count":    {1, 1, builtinCount},
+	"below":    {1, 1, builtinBelow},
+	"follows":  {1, 1, builtinFollows},
+	"in":       {1, 1, builtinIn},
+	"type":     {0, 0, builtinType},
+	"attrs":    {0, 1, builtinAttrs},
+	"line":     {0, 0, builtinLine},
+	"column":   {0, 0, builtinColumn},
+	"file":     {0, 0, builtinFile},
+	"len":      {1, 1, builtinLen},
+	"lower":    {1, 1, builtinLower},
+	"upper":    {1, 1, builtinUpper},
+	"substr":   {2, 3, builtinSubstr},
+	"contains": {2, 2, builtinContains},
+}
+
+func (e *evaluation) evalFuncCall(call *FuncCall, node ast.Node) (Value, error) {
+	def, ok := e.lookupFunc(call.ID)
+
+	if !ok {
+		return nil, fmt.Errorf("unknown function: %s()", call.ID)
+	}
+
+	var args []Value
+
+	for _, arg := range call.Args {
+		v, err := e.evalExpr(arg, node)
+
+		if err != nil {
+			return nil, err
+		}
+
+		args = append(args, v)
+	}
+
+	return def.call(call.ID, &EvalContext{Node: node, Fset: e.Fset, tree: e.tree}, args)
+}
+
+func argNum(args []Value, i int) (Num, error) {
+	if n, ok := args[i].(Num); ok {
+		return n, nil
+	}
+
+	return 0, fmt.Errorf("argument #%d should be number, got %s", i+1, args[i].TypeName())
+}
+
+func argStr(args []Value, i int) (string, error) {
+	if s, ok := args[i].(Str); ok {
+		return string(s), nil
+	}
+
+	return "", fmt.Errorf("argument #%d should be string, got %s", i+1, args[i].TypeName())
+}
+
+func argNodes(args []Value, i int) (NodeSet, error) {
+	if nodes, ok := args[i].(NodeSet); ok {
+		return nodes, nil
+	}
+
+	return nil, fmt.Errorf("argument #%d should be nodeset, got %s", i+1, args[i].TypeName())
+}
+
+func builtinDepth(ctx *EvalContext, args ...Value) (Value, error) {
+	return Num(ctx.Depth()), nil
+}
+
+func builtinPos(ctx *EvalContext, args ...Value) (Value, error) {
+	return Num(ctx.Pos()), nil
+}
+
+func builtinNth(ctx *EvalContext, args ...Value) (Value, error) {
+	n, err := argNum(args, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	left, right := ctx.tree.siblings(ctx.Node)
+
+	if n < 0 {
+		return Bool(Num(len(right)+1) == -n), nil
+	}
+
+	return Bool(Num(len(left)+1) == n), nil
+}
+
+func builtinFirst(ctx *EvalContext, args ...Value) (Value, error) {
+	return builtinNth(ctx, Num(1))
+}
+
+func builtinLast(ctx *EvalContext, args ...Value) (Value, error) {
+	return builtinNth(ctx, Num(-1))
+}
+
+func builtinCount(ctx *EvalContext, args ...Value) (Value, error) {
+	nodes, err := argNodes(args, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return Num(len(nodes)), nil
+}
+
+func builtinBelow(ctx *EvalContext, args ...Value) (Value, error) {
+	nodes, err := argNodes(args, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, parent := range ctx.tree.ancestors(ctx.Node) {
+		if nodes.Contains(parent) {
+			return Bool(true), nil
+		}
+	}
+
+	return Bool(false), nil
+}
+
+func builtinFollows(ctx *EvalContext, args ...Value) (Value, error) {
+	nodes, err := argNodes(args, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	order := ctx.tree.order(ctx.Node)
+
+	for _, node := range nodes {
+		if n := ctx.tree.order(node); n >= 0 && n < order {
+			return Bool(true), nil
+		}
+	}
+
+	return Bool(false), nil
+}
+
+func builtinIn(ctx *EvalContext, args ...Value) (Value, error) {
+	nodes, err := argNodes(args, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return Bool(nodes.Contains(ctx.Node)), nil
+}
+
+func builtinType(ctx *EvalContext, args ...Value) (Value, error) {
+	return Str(ctx.tree.typeOf(ctx.Node)), nil
+}
+
+func builtinAttrs(ctx *EvalContext, args ...Value) (Value, error) {
+	sep := ","
+
+	if len(args) > 0 {
+		s, err := argStr(args, 0)
+
+		if err != nil {
+			return nil, err
+		}
+
+		sep = s
+	}
+
+	names := ctx.tree.attrNames(ctx.Node)
+
+	sort.Strings(names)
+
+	return Str(strings.Join(names, sep)), nil
+}
+
+func builtinLine(ctx *EvalContext, args ...Value) (Value, error) {
+	pos, err := ctx.Position()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return Num(pos.Line), nil
+}
+
+func builtinColumn(ctx *EvalContext, args ...Value) (Value, error) {
+	pos, err := ctx.Position()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return Num(pos.Column), nil
+}
+
+func builtinFile(ctx *EvalContext, args ...Value) (Value, error) {
+	pos, err := ctx.Position()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return Str(pos.Filename), nil
+}
+
+func builtinLen(ctx *EvalContext, args ...Value) (Value, error) {
+	if nodes, ok := args[0].(NodeSet); ok {
+		return Num(len(nodes)), nil
+	}
+
+	s, err := argStr(args, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return Num(utf8.RuneCountInString(s)), nil
+}
+
+func builtinLower(ctx *EvalContext, args ...Value) (Value, error) {
+	s, err := argStr(args, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return Str(strings.ToLower(s)), nil
+}
+
+func builtinUpper(ctx *EvalContext, args ...Value) (Value, error) {
+	s, err := argStr(args, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return Str(strings.ToUpper(s)), nil
+}
+
+// builtinSubstr returns the substring which starts at the rune offset (counting from 0),
+// with the optional length, the negative offset counts from the end of string.
+func builtinSubstr(ctx *EvalContext, args ...Value) (Value, error) {
+	s, err := argStr(args, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	start, err := argNum(args, 1)
+
+	if err != nil {
+		return nil, err
+	}
+
+	runes := []rune(s)
+	size := Num(len(runes))
+
+	if start < 0 {
+		start += size
+	}
+
+	if start < 0 {
+		start = 0
+	} else if start > size {
+		start = size
+	}
+
+	end := size
+
+	if len(args) > 2 {
+		n, err := argNum(args, 2)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if n < 0 {
+			return nil, fmt.Errorf("negative length: %d", n)
+		}
+
+		// compare without adding, the sum may overflow
+		if n < end-start {
+			end = start + n
+		}
+	}
+
+	return Str(string(runes[start:end])), nil
+}
+
+func builtinContains(ctx *EvalContext, args ...Value) (Value, error) {
+	s, err := argStr(args, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sub, err := argStr(args, 1)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return Bool(strings.Contains(s, sub)), nil
+}
@@ -0,0 +1,175 @@
+package selector
+
+import (
+	"errors"
+	"go/ast"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const funcsSource = `
+package test
+
+func Foo(a, b int) {
+	go a()
+
+	for {
+		go b()
+	}
+}
+
+func Bar() string {
+	return "Hello World"
+}
+`
+
+func TestFuncs(t *testing.T) {
+	Convey("Given a parsed file", t, func() {
+		fset, f := parseSource(funcsSource)
+
+		e := NewEvaluator(fset)
+
+		eval := func(q string) ([]ast.Node, error) {
+			parsed, err := ParseQuery(q)
+
+			if err != nil {
+				return nil, err
+			}
+
+			return e.Eval(parsed, f)
+		}
+
+		Convey("When call the functions", func() {
+			var queries = map[string][]string{
+				"// FuncDecl [ count(.// GoStmt) == 2 ]":                                   {"FuncDecl"},
+				"// FuncDecl [ count(.// GoStmt) == 0 ]":                                   {"FuncDecl"},
+				"// * [ depth() == 2 ]":                                                    {"Ident", "FuncDecl", "FuncDecl"},
+				"// FuncDecl [ pos() == 3 ]":                                               {"FuncDecl"},
+				"// Field / * [ nth(1) ]":                                                  {"Ident", "Ident"},
+				"// Field / * [ nth(0 - 1) ]":                                              {"Ident", "Ident"},
+				"// Field / * [ nth(2) && nth(0 - 2) ]":                                    {"Ident"},
+				"// BlockStmt / * [ first() ]":                                             {"GoStmt", "GoStmt", "ReturnStmt"},
+				"// BlockStmt / * [ last() ]":                                              {"ForStmt", "GoStmt", "ReturnStmt"},
+				"// GoStmt [ below(..// ForStmt) ]":                                        {"GoStmt"},
+				"// GoStmt [ follows(..// File // ForStmt) ]":                              {"GoStmt"},
+				"// * [ follows(..// File // ForStmt) && type() == \"FuncDecl\" ]":         {"FuncDecl"},
+				"// Ident [ in(..// File // CallExpr / *) ]":                               {"Ident", "Ident"},
+				"// * [ attrs() == \"kind,value\" ]":                                       {"BasicLit"},
+				"// * [ attrs(\" \") == \"exported method name\" ]":                        {"FuncDecl", "FuncDecl"},
+				"// FuncDecl [ line() == 12 && column() == 1 ]":                            {"FuncDecl"},
+				"// FuncDecl [ file() == \"test.go\" ]":                                    {"FuncDecl", "FuncDecl"},
+				"// BasicLit [ len(@value) == 11 ]":                                        {"BasicLit"},
+				"// BasicLit [ lower(@value) == \"hello world\" ]":                         {"BasicLit"},
+				"// BasicLit [ upper(@value) == \"HELLO WORLD\" ]":                         {"BasicLit"},
+				"// BasicLit [ substr(@value, 6) == \"World\" ]":                           {"BasicLit"},
+				"// BasicLit [ substr(@value, 0, 5) == \"Hello\" ]":                        {"BasicLit"},
+				"// BasicLit [ substr(@value, 0 - 3, 2) == \"rl\" ]":                       {"BasicLit"},
+				"// BasicLit [ substr(@value, 1, 9223372036854775807) == \"ello World\" ]": {"BasicLit"},
+				"// BasicLit [ contains(@value, \"o W\") ]":                                {"BasicLit"},
+				"// FuncDecl [ len(/ Ident) == 1 ]":                                        {"FuncDecl", "FuncDecl"},
+			}
+
+			for q, expected := range queries {
+				nodes, err := eval(q)
+
+				So(err, ShouldBeNil)
+
+				if len(expected) == 0 {
+					So(nodes, ShouldBeEmpty)
+				} else {
+					So(typeNames(nodes), ShouldResemble, expected)
+				}
+			}
+		})
+
+		Convey("When call the functions with wrong arguments", func() {
+			var queries = map[string]string{
+				"* [ unknown() ]":               "unknown function: unknown()",
+				"* [ nth() ]":                   "nth() expects 1 argument(s), got 0",
+				"* [ attrs(1, 2) ]":             "attrs() expects 0 to 1 argument(s), got 2",
+				"* [ nth(\"1\") ]":              "nth(): argument #1 should be number, got string",
+				"* [ count(1) ]":                "count(): argument #1 should be nodeset, got number",
+				"* [ upper(1) ]":                "upper(): argument #1 should be string, got number",
+				"* [ substr(\"a\", 0, 0 - 1) ]": "substr(): negative length: -1",
+			}
+
+			for q, expected := range queries {
+				_, err := eval(q)
+
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, expected)
+			}
+		})
+
+		Convey("When call the position functions without file set", func() {
+			_, err := evalQuery(f, "* [ line() ]")
+
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "line(): no file set to resolve the position")
+		})
+	})
+}
+
+func TestRegisterFunc(t *testing.T) {
+	Convey("Given a parsed file", t, func() {
+		_, f := parseSource(evalSource)
+
+		isLogger := func(ctx *EvalContext, args ...Value) (Value, error) {
+			call, ok := ctx.Node.(*ast.CallExpr)
+
+			if !ok {
+				return Bool(false), nil
+			}
+
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+
+			if !ok {
+				return Bool(false), nil
+			}
+
+			pkg, ok := sel.X.(*ast.Ident)
+
+			return Bool(ok && pkg.Name == "fmt" && strings.HasPrefix(sel.Sel.Name, "Print")), nil
+		}
+
+		q, err := ParseQuery("// CallExpr [ isLogger() ]")
+
+		So(err, ShouldBeNil)
+
+		Convey("When register a function to an evaluator", func() {
+			e := NewEvaluator(nil)
+			e.RegisterFunc("isLogger", isLogger)
+
+			nodes, err := e.Eval(q, f)
+
+			So(err, ShouldBeNil)
+			So(nodes, ShouldHaveLength, 1)
+			So(nodes[0].(*ast.CallExpr).Fun, ShouldHaveSameTypeAs, &ast.SelectorExpr{})
+
+			Convey("The function should not be visible to other evaluators", func() {
+				_, err := NewEvaluator(nil).Eval(q, f)
+
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "unknown function: isLogger()")
+			})
+		})
+
+		Convey("When register a function which overrides the built-in function", func() {
+			e := NewEvaluator(nil)
+			e.RegisterFunc("upper", func(ctx *EvalContext, args ...Value) (Value, error) {
+				return nil, errors.New("not implemented")
+			})
+
+			q, err := ParseQuery("// FuncDecl [ upper(@name) == \"FOO\" ]")
+
+			So(err, ShouldBeNil)
+
+			_, err = e.Eval(q, f)
+
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "upper(): not implemented")
+		})
+	})
+}
@@ -0,0 +1,646 @@
+package selector
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"unicode/utf8"
+)
+
+// The path expressions and expressions are encoded as JSON objects with the discriminator field `type`, e.g.
+//
+//	{"type": "binary", "lhs": {"type": "attr", "name": "name"}, "op": "==", "rhs": {"type": "str", "value": "Foo"}}
+//
+// The names and strings must be valid UTF-8 to be encoded, the integers beyond ±(2^53-1)
+// and the infinite or NaN floats are encoded as strings, since they have no exact JSON number of float64.
+const (
+	jsonPath    = "path"
+	jsonSet     = "set"
+	jsonStep    = "step"
+	jsonCond    = "cond"
+	jsonUnary   = "unary"
+	jsonBinary  = "binary"
+	jsonCall    = "call"
+	jsonAttr    = "attr"
+	jsonRegexp  = "regexp"
+	jsonStr     = "str"
+	jsonNum     = "num"
+	jsonFloat   = "float"
+	jsonKeyword = "keyword"
+	jsonParam   = "param"
+)
+
+// checkUTF8 rejects the string which can't be encoded in JSON without replacing the invalid bytes.
+func checkUTF8(strs ...string) error {
+	for _, s := range strs {
+		if !utf8.ValidString(s) {
+			return fmt.Errorf("invalid UTF-8 string %s", quote(s))
+		}
+	}
+
+	return nil
+}
+
+// rawExpr decodes the expression by the discriminator field.
+type rawExpr struct {
+	Expr
+}
+
+func (r *rawExpr) UnmarshalJSON(data []byte) (err error) {
+	r.Expr, err = unmarshalExpr(data)
+
+	return
+}
+
+// rawPathExpr decodes the path expression by the discriminator field.
+type rawPathExpr struct {
+	PathExpr
+}
+
+func (r *rawPathExpr) UnmarshalJSON(data []byte) (err error) {
+	r.PathExpr, err = unmarshalPathExpr(data)
+
+	return
+}
+
+func jsonNodeType(data []byte) (string, error) {
+	var node struct {
+		Type string `json:"type"`
+	}
+
+	if err := json.Unmarshal(data, &node); err != nil {
+		return "", err
+	}
+
+	if len(node.Type) == 0 {
+		return "", fmt.Errorf("missing node type in %s", data)
+	}
+
+	return node.Type, nil
+}
+
+// unmarshalNode decodes the node after checking its discriminator field.
+func unmarshalNode(data []byte, typ string, v interface{}) error {
+	t, err := jsonNodeType(data)
+
+	if err != nil {
+		return err
+	}
+
+	if t != typ {
+		return fmt.Errorf("unexpected node type `%s`, expected `%s`", t, typ)
+	}
+
+	return json.Unmarshal(data, v)
+}
+
+func unmarshalPathExpr(data []byte) (PathExpr, error) {
+	t, err := jsonNodeType(data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch t {
+	case jsonPath:
+		var p Path
+
+		if err := p.UnmarshalJSON(data); err != nil {
+			return nil, err
+		}
+
+		return p, nil
+
+	case jsonSet:
+		s := new(SetExpr)
+
+		if err := s.UnmarshalJSON(data); err != nil {
+			return nil, err
+		}
+
+		return s, nil
+
+	default:
+		return nil, fmt.Errorf("unknown path expression type `%s`", t)
+	}
+}
+
+func unmarshalExpr(data []byte) (Expr, error) {
+	if string(data) == "null" {
+		return nil, nil
+	}
+
+	t, err := jsonNodeType(data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var expr interface {
+		Expr
+		json.Unmarshaler
+	}
+
+	switch t {
+	case jsonPath:
+		expr = new(Path)
+	case jsonCond:
+		expr = new(Cond)
+	case jsonUnary:
+		expr = new(Unary)
+	case jsonBinary:
+		expr = new(Binary)
+	case jsonCall:
+		expr = new(FuncCall)
+	case jsonAttr:
+		expr = new(WithAttr)
+	case jsonRegexp:
+		expr = new(Regexp)
+	case jsonStr:
+		expr = new(Str)
+	case jsonNum:
+		expr = new(Num)
+	case jsonFloat:
+		expr = new(Float)
+	case jsonKeyword:
+		expr = new(Keyword)
+	case jsonParam:
+		expr = new(QueryParam)
+	default:
+		return nil, fmt.Errorf("unknown expression type `%s`", t)
+	}
+
+	if err := expr.UnmarshalJSON(data); err != nil {
+		return nil, err
+	}
+
+	// the literals and paths are used as values
+	switch v := expr.(type) {
+	case *Path:
+		return *v, nil
+	case *Str:
+		return *v, nil
+	case *Num:
+		return *v, nil
+	case *Float:
+		return *v, nil
+	case *Keyword:
+		return *v, nil
+	case *QueryParam:
+		return *v, nil
+	}
+
+	return expr, nil
+}
+
+func (q Query) MarshalJSON() ([]byte, error) {
+	exprs := []PathExpr(q)
+
+	if exprs == nil {
+		exprs = []PathExpr{}
+	}
+
+	return json.Marshal(exprs)
+}
+
+func (q *Query) UnmarshalJSON(data []byte) error {
+	var exprs []rawPathExpr
+
+	if err := json.Unmarshal(data, &exprs); err != nil {
+		return err
+	}
+
+	*q = nil
+
+	for _, expr := range exprs {
+		*q = append(*q, expr.PathExpr)
+	}
+
+	return nil
+}
+
+func (s *SetExpr) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type string   `json:"type"`
+		Lhs  PathExpr `json:"lhs"`
+		Op   string   `json:"op"`
+		Rhs  PathExpr `json:"rhs"`
+	}{jsonSet, s.Lhs, s.Op, s.Rhs})
+}
+
+func (s *SetExpr) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Lhs rawPathExpr `json:"lhs"`
+		Op  string      `json:"op"`
+		Rhs rawPathExpr `json:"rhs"`
+	}
+
+	if err := unmarshalNode(data, jsonSet, &v); err != nil {
+		return err
+	}
+
+	*s = SetExpr{v.Lhs.PathExpr, v.Op, v.Rhs.PathExpr}
+
+	return nil
+}
+
+func (p Path) MarshalJSON() ([]byte, error) {
+	steps := []*Step(p)
+
+	if steps == nil {
+		steps = []*Step{}
+	}
+
+	return json.Marshal(struct {
+		Type  string  `json:"type"`
+		Steps []*Step `json:"steps"`
+	}{jsonPath, steps})
+}
+
+func (p *Path) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Steps []*Step `json:"steps"`
+	}
+
+	if err := unmarshalNode(data, jsonPath, &v); err != nil {
+		return err
+	}
+
+	*p = Path(v.Steps)
+
+	return nil
+}
+
+type jsonStepFields struct {
+	Axis   *Axis  `json:"axis,omitempty"`
+	Match  string `json:"match,omitempty"`
+	Not    *Step  `json:"not,omitempty"`
+	Ref    *Ref   `json:"ref,omitempty"`
+	Result bool   `json:"result,omitempty"`
+	Slice  *Slice `json:"slice,omitempty"`
+}
+
+func (s *Step) MarshalJSON() ([]byte, error) {
+	strs := []string{s.Match}
+
+	if s.Axis != nil {
+		strs = append(strs, s.Axis.Type)
+	}
+
+	if s.Ref != nil {
+		strs = append(strs, s.Ref.Name)
+	}
+
+	if err := checkUTF8(strs...); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		jsonStepFields
+		Filter Expr `json:"filter,omitempty"`
+	}{jsonStep, jsonStepFields{s.Axis, s.Match, s.Not, s.Ref, s.Result, s.Slice}, s.Filter})
+}
+
+func (s *Step) UnmarshalJSON(data []byte) error {
+	var v struct {
+		jsonStepFields
+		Filter rawExpr `json:"filter"`
+	}
+
+	if err := unmarshalNode(data, jsonStep, &v); err != nil {
+		return err
+	}
+
+	*s = Step{v.Axis, v.Match, v.Not, v.Ref, v.Result, v.Filter.Expr, v.Slice}
+
+	return nil
+}
+
+func (c *Cond) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		Cond Expr   `json:"cond"`
+		Then Expr   `json:"then,omitempty"`
+		Else Expr   `json:"else"`
+	}{jsonCond, c.Cond, c.Then, c.Else})
+}
+
+func (c *Cond) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Cond rawExpr `json:"cond"`
+		Then rawExpr `json:"then"`
+		Else rawExpr `json:"else"`
+	}
+
+	if err := unmarshalNode(data, jsonCond, &v); err != nil {
+		return err
+	}
+
+	*c = Cond{v.Cond.Expr, v.Then.Expr, v.Else.Expr}
+
+	return nil
+}
+
+func (u *Unary) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		Op   string `json:"op"`
+		Expr Expr   `json:"expr"`
+	}{jsonUnary, u.Op, u.Expr})
+}
+
+func (u *Unary) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Op   string  `json:"op"`
+		Expr rawExpr `json:"expr"`
+	}
+
+	if err := unmarshalNode(data, jsonUnary, &v); err != nil {
+		return err
+	}
+
+	*u = Unary{v.Op, v.Expr.Expr}
+
+	return nil
+}
+
+func (b *Binary) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		Lhs  Expr   `json:"lhs"`
+		Op   string `json:"op"`
+		Rhs  Expr   `json:"rhs"`
+	}{jsonBinary, b.Lhs, b.Op, b.Rhs})
+}
+
+func (b *Binary) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Lhs rawExpr `json:"lhs"`
+		Op  string  `json:"op"`
+		Rhs rawExpr `json:"rhs"`
+	}
+
+	if err := unmarshalNode(data, jsonBinary, &v); err != nil {
+		return err
+	}
+
+	*b = Binary{v.Lhs.Expr, v.Op, v.Rhs.Expr}
+
+	return nil
+}
+
+func (c *FuncCall) MarshalJSON() ([]byte, error) {
+	if err := checkUTF8(c.ID); err != nil {
+		return nil, err
+	}
+
+	args := c.Args
+
+	if args == nil {
+		args = []Expr{}
+	}
+
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		Name string `json:"name"`
+		Args []Expr `json:"args"`
+	}{jsonCall, c.ID, args})
+}
+
+func (c *FuncCall) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Name string    `json:"name"`
+		Args []rawExpr `json:"args"`
+	}
+
+	if err := unmarshalNode(data, jsonCall, &v); err != nil {
+		return err
+	}
+
+	*c = FuncCall{ID: v.Name}
+
+	for _, arg := range v.Args {
+		c.Args = append(c.Args, arg.Expr)
+	}
+
+	return nil
+}
+
+func (a *WithAttr) MarshalJSON() ([]byte, error) {
+	if err := checkUTF8(a.ID); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		Name string `json:"name"`
+	}{jsonAttr, a.ID})
+}
+
+func (a *WithAttr) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Name string `json:"name"`
+	}
+
+	if err := unmarshalNode(data, jsonAttr, &v); err != nil {
+		return err
+	}
+
+	*a = WithAttr{v.Name}
+
+	return nil
+}
+
+// MarshalJSON encodes the regex with the leading flags group, e.g. `(?i)foo`
+func (r *Regexp) MarshalJSON() ([]byte, error) {
+	if err := checkUTF8(r.Regexp.String()); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	}{jsonRegexp, r.Regexp.String()})
+}
+
+func (r *Regexp) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Value string `json:"value"`
+	}
+
+	if err := unmarshalNode(data, jsonRegexp, &v); err != nil {
+		return err
+	}
+
+	re, err := regexp.Compile(v.Value)
+
+	if err != nil {
+		return err
+	}
+
+	r.Regexp = re
+
+	return nil
+}
+
+type jsonValue struct {
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+func (s Str) MarshalJSON() ([]byte, error) {
+	if err := checkUTF8(string(s)); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(jsonValue{jsonStr, string(s)})
+}
+
+func (s *Str) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Value string `json:"value"`
+	}
+
+	if err := unmarshalNode(data, jsonStr, &v); err != nil {
+		return err
+	}
+
+	*s = Str(v.Value)
+
+	return nil
+}
+
+// maxSafeInteger is the largest integer which is exactly represented by the float64 of JSON consumers, e.g. JavaScript.
+const maxSafeInteger = 1<<53 - 1
+
+// MarshalJSON encodes the number as JSON number, or string if it is out of the safe integers of float64.
+func (n Num) MarshalJSON() ([]byte, error) {
+	if n > maxSafeInteger || n < -maxSafeInteger {
+		return json.Marshal(jsonValue{jsonNum, strconv.FormatInt(int64(n), 10)})
+	}
+
+	return json.Marshal(jsonValue{jsonNum, int64(n)})
+}
+
+func (n *Num) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Value json.RawMessage `json:"value"`
+	}
+
+	if err := unmarshalNode(data, jsonNum, &v); err != nil {
+		return err
+	}
+
+	var s string
+
+	if err := json.Unmarshal(v.Value, &s); err == nil {
+		x, err := strconv.ParseInt(s, 10, 64)
+
+		if err != nil {
+			return err
+		}
+
+		*n = Num(x)
+
+		return nil
+	}
+
+	var x int64
+
+	if err := json.Unmarshal(v.Value, &x); err != nil {
+		return err
+	}
+
+	*n = Num(x)
+
+	return nil
+}
+
+// MarshalJSON encodes the float as number, or string for the infinities and NaN which have no JSON number.
+func (f Float) MarshalJSON() ([]byte, error) {
+	if math.IsInf(float64(f), 0) || math.IsNaN(float64(f)) {
+		return json.Marshal(jsonValue{jsonFloat, strconv.FormatFloat(float64(f), 'g', -1, 64)})
+	}
+
+	return json.Marshal(jsonValue{jsonFloat, float64(f)})
+}
+
+func (f *Float) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Value json.RawMessage `json:"value"`
+	}
+
+	if err := unmarshalNode(data, jsonFloat, &v); err != nil {
+		return err
+	}
+
+	var s string
+
+	if err := json.Unmarshal(v.Value, &s); err == nil {
+		x, err := strconv.ParseFloat(s, 64)
+
+		if err != nil {
+			return err
+		}
+
+		*f = Float(x)
+
+		return nil
+	}
+
+	var x float64
+
+	if err := json.Unmarshal(v.Value, &x); err != nil {
+		return err
+	}
+
+	*f = Float(x)
+
+	return nil
+}
+
+func (k Keyword) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonValue{jsonKeyword, string(k)})
+}
+
+func (k *Keyword) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Value string `json:"value"`
+	}
+
+	if err := unmarshalNode(data, jsonKeyword, &v); err != nil {
+		return err
+	}
+
+	*k = Keyword(v.Value)
+
+	return nil
+}
+
+func (p QueryParam) MarshalJSON() ([]byte, error) {
+	if err := checkUTF8(string(p)); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		Name string `json:"name"`
+	}{jsonParam, string(p)})
+}
+
+func (p *QueryParam) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Name string `json:"name"`
+	}
+
+	if err := unmarshalNode(data, jsonParam, &v); err != nil {
+		return err
+	}
+
+	*p = QueryParam(v.Name)
+
+	return nil
+}
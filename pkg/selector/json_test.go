@@ -0,0 +1,134 @@
+package selector
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/quick"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestJSON(t *testing.T) {
+	Convey("Given the parsed queries", t, func() {
+		Convey("When marshal a query", func() {
+			q, err := ParseQuery(`//:Decls FuncDecl [@name == "Foo"] / Ident [1]`)
+
+			So(err, ShouldBeNil)
+
+			data, err := json.Marshal(q)
+
+			So(err, ShouldBeNil)
+			So(string(data), ShouldEqual, `[{"type":"path","steps":[{"type":"step",`+
+				`"axis":{"dir":"//","type":"Decls"},"match":"FuncDecl",`+
+				`"filter":{"type":"binary","lhs":{"type":"attr","name":"name"},"op":"==","rhs":{"type":"str","value":"Foo"}}},`+
+				`{"type":"step","axis":{"dir":"/"},"match":"Ident","slice":{"start":1,"end":1}}]}]`)
+		})
+
+		Convey("When marshal a query with invalid UTF-8 string", func() {
+			q, err := ParseQuery(`// BasicLit [ @value == "\xff" ]`)
+
+			So(err, ShouldBeNil)
+
+			_, err = json.Marshal(q)
+
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEndWith, `invalid UTF-8 string "\xff"`)
+		})
+
+		Convey("When marshal the large integers", func() {
+			var numbers = map[Num]string{
+				9007199254740991:     `{"type":"num","value":9007199254740991}`,
+				-9007199254740991:    `{"type":"num","value":-9007199254740991}`,
+				9007199254740993:     `{"type":"num","value":"9007199254740993"}`,
+				-9223372036854775808: `{"type":"num","value":"-9223372036854775808"}`,
+			}
+
+			for n, encoded := range numbers {
+				data, err := json.Marshal(n)
+
+				So(err, ShouldBeNil)
+				So(string(data), ShouldEqual, encoded)
+
+				var decoded Num
+
+				So(json.Unmarshal(data, &decoded), ShouldBeNil)
+				So(decoded, ShouldEqual, n)
+			}
+
+			var n Num
+
+			So(json.Unmarshal([]byte(`{"type":"num","value":"x"}`), &n), ShouldNotBeNil)
+		})
+
+		Convey("When unmarshal the marshaled queries", func() {
+			var queries = []string{
+				"// FuncDecl ! / Ident, // CallExpr [2:-1]",
+				"// FuncDecl - // FuncDecl [ @method ] & $funcs",
+				"// not(FuncDecl [ @exported ]) [ ! @method ]",
+				"// * [ (@value == 1.5 || @value < -2) && @name !~ `^Test`i ]",
+				"// * [ @a ? ~ @b : (@c ?: {name}) ]",
+				"// * [ len(@args) > 0 && (substr(@name, 1) =~ `x` || @x == null) ]",
+				"// FuncDecl [ // ReturnStmt [ / BasicLit ] ]",
+				"//:Results Field [ (/ Ident) / 2 == true ]",
+				"// BasicLit [ @value == 9223372036854775807 ]",
+			}
+
+			for _, s := range queries {
+				q, err := ParseQuery(s)
+
+				So(err, ShouldBeNil)
+
+				data, err := json.Marshal(q)
+
+				So(err, ShouldBeNil)
+
+				var decoded Query
+
+				So(json.Unmarshal(data, &decoded), ShouldBeNil)
+				So(decoded, ShouldResemble, q)
+				So(decoded.String(), ShouldEqual, q.String())
+			}
+		})
+
+		Convey("When unmarshal the invalid queries", func() {
+			var queries = map[string]string{
+				`[{"steps":[]}]`:   `missing node type in {"steps":[]}`,
+				`[{"type":"foo"}]`: "unknown path expression type `foo`",
+				`[{"type":"path","steps":[{"type":"path"}]}]`:                                        "unexpected node type `path`, expected `step`",
+				`[{"type":"path","steps":[{"type":"step","filter":{"type":"bar"}}]}]`:                "unknown expression type `bar`",
+				`[{"type":"path","steps":[{"type":"step","filter":{"type":"regexp","value":"("}}]}]`: "error parsing regexp: missing closing ): `(`",
+			}
+
+			for s, msg := range queries {
+				var q Query
+
+				err := json.Unmarshal([]byte(s), &q)
+
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, msg)
+			}
+		})
+	})
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	Convey("Given the random queries built by the builder", t, func() {
+		roundTrip := func(q randomQuery) bool {
+			data, err := json.Marshal(q.Query)
+
+			if err != nil {
+				return strings.Contains(err.Error(), "invalid UTF-8 string")
+			}
+
+			var decoded Query
+
+			return json.Unmarshal(data, &decoded) == nil && reflect.DeepEqual(decoded, q.Query)
+		}
+
+		Convey("Then the marshaled query should be unmarshaled to an equal query", func() {
+			So(quick.Check(roundTrip, &quick.Config{MaxCount: 1000}), ShouldBeNil)
+		})
+	})
+}
@@ -3,11 +3,12 @@ package selector
 import (
 	"bufio"
 	"bytes"
-	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"regexp"
 	"strconv"
+	"strings"
 	"unicode"
 	"unicode/utf8"
 
@@ -18,12 +19,38 @@ const eof = 0
 
 type queryLexerImpl struct {
 	*bufio.Reader
+	src    *sourceReader
 	result Query
 	errs   *multierror.Error
+
+	library bool          // lex the library instead of query
+	defs    []*Definition // the definitions of library
+	imports []string      // the imports of library
+
+	start  int   // the offset of the last token
+	tokens []int // the lexed tokens
+	replay []int // the tokens to replay instead of lexing the input
+	failed []int // the indexes of the replayed tokens which fail the parser
+}
+
+// sourceReader keeps the consumed input to locate the syntax errors.
+type sourceReader struct {
+	io.Reader
+	buf bytes.Buffer
+}
+
+func (r *sourceReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+
+	r.buf.Write(p[:n])
+
+	return n, err
 }
 
 func queryNewLexer(r io.Reader) *queryLexerImpl {
-	return &queryLexerImpl{Reader: bufio.NewReader(r)}
+	src := &sourceReader{Reader: r}
+
+	return &queryLexerImpl{Reader: bufio.NewReader(src), src: src}
 }
 
 func (l *queryLexerImpl) Result() Query {
@@ -31,19 +58,112 @@ func (l *queryLexerImpl) Result() Query {
 }
 
 func (l *queryLexerImpl) Err() error {
-	return l.errs.ErrorOrNil()
+	if l.errs == nil {
+		return nil
+	}
+
+	io.Copy(ioutil.Discard, l.Reader)
+
+	for _, err := range l.errs.Errors {
+		err.(*SyntaxError).Query = l.src.buf.String()
+	}
+
+	return l.errs
+}
+
+// offset returns the offset of the next unread character.
+func (l *queryLexerImpl) offset() int {
+	return l.src.buf.Len() - l.Buffered()
 }
 
 func (l *queryLexerImpl) Error(s string) {
-	l.errs = multierror.Append(l.errs, errors.New(s))
+	if l.replay != nil {
+		l.failed = append(l.failed, len(l.tokens)-1)
+
+		return
+	}
+
+	n := len(l.tokens) - 1
+
+	if n >= 0 && l.tokens[n] == ERR {
+		return // already reported by the lexer
+	}
+
+	l.report(&SyntaxError{
+		Token:    string(l.src.buf.Bytes()[l.start:l.offset()]),
+		Expected: l.expected(l.tokens[:n]),
+	})
+}
+
+func (l *queryLexerImpl) report(err *SyntaxError) {
+	src := l.src.buf.Bytes()[:l.start]
+
+	err.Offset = l.start
+	err.Line = bytes.Count(src, []byte("\n")) + 1
+	err.Column = len(src) - (bytes.LastIndexByte(src, '\n') + 1) + 1
+
+	l.errs = multierror.Append(l.errs, err)
+}
+
+// expected returns the names of tokens which could follow the lexed tokens.
+func (l *queryLexerImpl) expected(tokens []int) (names []string) {
+	for _, tok := range expectedTokens {
+		replay := &queryLexerImpl{replay: append(append([]int{}, tokens...), tok, eof)}
+
+		queryParse(replay)
+
+		if !replay.failedAt(len(tokens)) {
+			names = append(names, tokenNames[tok])
+		}
+	}
+
+	return
+}
+
+func (l *queryLexerImpl) failedAt(i int) bool {
+	for _, n := range l.failed {
+		if n == i {
+			return true
+		}
+	}
+
+	return false
 }
 
 func (l *queryLexerImpl) Lex(lval *querySymType) int {
+	var tok int
+
+	if l.replay != nil {
+		if len(l.tokens) < len(l.replay) {
+			tok = l.replay[len(l.tokens)]
+		}
+	} else if l.library && len(l.tokens) == 0 {
+		tok = LIBRARY
+	} else if tok = l.lex(lval); tok == ERR {
+		l.report(&SyntaxError{Token: string(l.src.buf.Bytes()[l.start:l.offset()]), Err: lval.err})
+	}
+
+	l.tokens = append(l.tokens, tok)
+
+	return tok
+}
+
+func (l *queryLexerImpl) lex(lval *querySymType) int {
 	for {
+		l.start = l.offset()
+
 		c := l.next()
 
 		switch c {
-		case '[', ']', '(', ')', ':', '@', '.', '~', ',', '+', '-', '*', '/', '^', '%':
+		case '/', '.', '-', '+', '~', '<', '>':
+			if dir := l.axis(c); len(dir) > 0 {
+				lval.str = dir
+				return AXIS
+			}
+		}
+
+		switch c {
+		case '[', ']', '(', ')', '{', '}', ':', '@', '.', '~', ',', '+', '-', '*', '/', '^', '%', ';':
 			break
 
 		case '<':
@@ -131,18 +251,29 @@ func (l *queryLexerImpl) Lex(lval *querySymType) int {
 
 		case '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
 			l.UnreadRune()
-			lval.num, lval.err = l.num()
 
-			if lval.err != nil {
-				return ERR
-			}
-			return NUM
+			return l.num(lval)
 
 		case ' ', '\t', '\r', '\n':
 			continue
 
-		case '"':
-			lval.str, lval.err = l.str()
+		case '#':
+			for c != '\n' && c != eof {
+				c = l.next()
+			}
+
+			continue
+
+		case '$':
+			if lval.str = l.id(); len(lval.str) == 0 {
+				lval.err = fmt.Errorf("missing the name of reference")
+				return ERR
+			}
+
+			return REF
+
+		case '"', '\'':
+			lval.str, lval.err = l.str(c)
 
 			if lval.err != nil {
 				return ERR
@@ -164,6 +295,28 @@ func (l *queryLexerImpl) Lex(lval *querySymType) int {
 			if unicode.IsLetter(c) || c == '_' {
 				l.UnreadRune()
 				lval.str = l.id()
+
+				switch lval.str {
+				case "true":
+					return TRUE
+				case "false":
+					return FALSE
+				case "null":
+					return NULL
+				case "not":
+					if buf, err := l.Peek(1); err == nil && buf[0] == '(' {
+						return NOT
+					}
+				case "let":
+					if l.library {
+						return LET
+					}
+				case "import":
+					if l.library {
+						return IMPORT
+					}
+				}
+
 				return ID
 			}
 
@@ -190,26 +343,139 @@ func (l *queryLexerImpl) next() rune {
 	return c
 }
 
-func (l *queryLexerImpl) num() (int64, error) {
-	buf := new(bytes.Buffer)
+// axis scans the axis direction which starts with the character,
+// or returns an empty string if it is not an axis direction, e.g. `.//` or `-/`.
+func (l *queryLexerImpl) axis(c rune) string {
+	var dir string
 
-	var c rune
+	switch c {
+	case '/':
+		if l.skip("/") {
+			return "//"
+		}
 
-	for {
-		c = l.next()
+		return ""
 
-		if unicode.IsNumber(c) {
-			buf.WriteRune(c)
+	case '.':
+		if l.skip("./") {
+			dir = "../"
+		} else if l.skip("/") {
+			dir = "./"
 		} else {
-			break
+			return ""
 		}
+
+	case '-', '+', '~':
+		if !l.skip("/") {
+			return ""
+		}
+
+		dir = string(c) + "/"
+
+	case '<', '>':
+		if l.skip("//") {
+			return string(c) + "//"
+		}
+
+		return ""
 	}
 
-	if c != eof {
-		l.UnreadRune()
+	if l.skip("/") {
+		dir += "/"
+	}
+
+	return dir
+}
+
+// skip consumes the following characters if they match s.
+func (l *queryLexerImpl) skip(s string) bool {
+	if buf, err := l.Peek(len(s)); err == nil && string(buf) == s {
+		l.Discard(len(s))
+
+		return true
+	}
+
+	return false
+}
+
+// num scans an integer in decimal, hex (0x), octal (0o or 0) or binary (0b) notation,
+// or a decimal float with optional fraction and exponent, e.g. `1.5` or `1e-3`.
+func (l *queryLexerImpl) num(lval *querySymType) int {
+	start := l.offset()
+	prefixed := false
+
+	if buf, err := l.Peek(2); err == nil && buf[0] == '0' && strings.IndexByte("xXoObB", buf[1]) >= 0 {
+		l.Discard(2)
+		prefixed = true
+	}
+
+	l.digits(prefixed)
+
+	float := false
+
+	if !prefixed {
+		if buf, err := l.Peek(2); err == nil && buf[0] == '.' && isDigit(buf[1]) {
+			l.Discard(1)
+			l.digits(false)
+			float = true
+		}
+
+		if buf, _ := l.Peek(3); len(buf) > 1 && (buf[0] == 'e' || buf[0] == 'E') {
+			n := 0
+
+			if isDigit(buf[1]) {
+				n = 1
+			} else if len(buf) > 2 && (buf[1] == '+' || buf[1] == '-') && isDigit(buf[2]) {
+				n = 2
+			}
+
+			if n > 0 {
+				l.Discard(n)
+				l.digits(false)
+				float = true
+			}
+		}
+	}
+
+	s := string(l.src.buf.Bytes()[start:l.offset()])
+
+	if float {
+		lval.float, lval.err = strconv.ParseFloat(s, 64)
+	} else {
+		lval.num, lval.err = strconv.ParseInt(s, 0, 64)
+	}
+
+	switch {
+	case lval.err != nil:
+		lval.err = fmt.Errorf("invalid number: %s", s)
+
+		return ERR
+	case float:
+		return FLOAT
+	default:
+		return NUM
+	}
+}
+
+// digits skips the digits and underscores, with the hex digits if prefixed.
+func (l *queryLexerImpl) digits(prefixed bool) {
+	for {
+		buf, err := l.Peek(1)
+
+		if err != nil {
+			return
+		}
+
+		if c := buf[0]; isDigit(c) || c == '_' || prefixed && ('a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			l.Discard(1)
+		} else {
+			return
+		}
 	}
+}
 
-	return strconv.ParseInt(buf.String(), 10, 64)
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
 }
 
 func (l *queryLexerImpl) id() string {
@@ -235,17 +501,20 @@ L:
 	return buf.String()
 }
 
-func (l *queryLexerImpl) str() (s string, err error) {
+// str scans a string quoted by the quote character.
+func (l *queryLexerImpl) str(quote rune) (s string, err error) {
 	buf := new(bytes.Buffer)
 
+	var c rune
+
 L:
 	for {
-		switch c := l.next(); {
+		switch c = l.next(); {
 		case c == eof:
-			err = fmt.Errorf("incomplete string: \"%s\"", buf.String())
+			err = fmt.Errorf("incomplete string: %c%s%c", quote, buf.String(), quote)
 			break L
 
-		case c == '"':
+		case c == quote:
 			s = buf.String()
 			break L
 
@@ -297,7 +566,7 @@ L:
 					v = (v << 4) | n
 				}
 
-				if c == 'x' {
+				if n == 2 {
 					buf.WriteByte(byte(v))
 				} else {
 					if v > utf8.MaxRune {
@@ -328,7 +597,7 @@ L:
 					break L
 				}
 
-				buf.WriteRune(v)
+				buf.WriteByte(byte(v))
 			default:
 				err = fmt.Errorf("unexpected escaped char: '%c'", c)
 				break L
@@ -338,6 +607,13 @@ L:
 		}
 	}
 
+	if err != nil && c != quote {
+		// skip the rest of the invalid string
+		for c != quote && c != eof {
+			c = l.next()
+		}
+	}
+
 	return
 }
 
@@ -364,7 +640,19 @@ L:
 			break L
 
 		case '`':
-			r, err := regexp.Compile(buf.String())
+			flags := l.regexpFlags()
+
+			if len(flags) > 0 {
+				for _, f := range flags {
+					if !strings.ContainsRune(regexpFlags, f) {
+						return nil, fmt.Errorf("unknown regex flag: %c", f)
+					}
+				}
+
+				flags = "(?" + flags + ")"
+			}
+
+			r, err := regexp.Compile(flags + buf.String())
 
 			if err != nil {
 				return nil, err
@@ -379,3 +667,29 @@ L:
 
 	return
 }
+
+// regexpFlags are the supported flags following a regex, e.g. `foo`i
+//
+//	i  case-insensitive
+//	m  multi-line mode, ^ and $ match begin/end line in addition to begin/end text
+//	s  let . match \n
+//	U  ungreedy, swap meaning of x* and x*?, x+ and x+?, etc
+const regexpFlags = "imsU"
+
+func (l *queryLexerImpl) regexpFlags() string {
+	buf := new(bytes.Buffer)
+
+	for {
+		c := l.next()
+
+		if !unicode.IsLetter(c) {
+			if c != eof {
+				l.UnreadRune()
+			}
+
+			return buf.String()
+		}
+
+		buf.WriteRune(c)
+	}
+}
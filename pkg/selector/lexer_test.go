@@ -9,7 +9,7 @@ import (
 )
 
 var tokens = []rune{
-	'[', ']', '(', ')', ':', '@', '.', '~', ',', '+', '-', '*', '/', '^', '%',
+	'[', ']', '(', ')', '{', '}', ':', '@', '.', '~', ',', '+', '-', '*', '/', '^', '%',
 }
 
 var operators = map[int]string{
@@ -65,6 +65,21 @@ func TestLexer(t *testing.T) {
 			}
 		})
 
+		Convey("When parse an axis direction", func() {
+			for _, dir := range []string{"//", "./", ".//", "-/", "-//", "+/", "+//", "~/", "~//", "../", "..//", "<//", ">//"} {
+				buf.WriteString(dir + " ")
+
+				So(lexer.Lex(lval), ShouldEqual, AXIS)
+				So(lval.str, ShouldEqual, dir)
+			}
+
+			buf.WriteString("/ - /")
+
+			So(lexer.Lex(lval), ShouldEqual, '/')
+			So(lexer.Lex(lval), ShouldEqual, '-')
+			So(lexer.Lex(lval), ShouldEqual, '/')
+		})
+
 		Convey("When parse a number", func() {
 			buf.WriteString("123")
 
@@ -72,6 +87,60 @@ func TestLexer(t *testing.T) {
 			So(lval.num, ShouldEqual, 123)
 		})
 
+		Convey("When parse an integer in other notations", func() {
+			for s, n := range map[string]int64{"0x1F": 31, "0o17": 15, "017": 15, "0b101": 5, "1_000": 1000} {
+				buf.WriteString(s + " ")
+
+				So(lexer.Lex(lval), ShouldEqual, NUM)
+				So(lval.num, ShouldEqual, n)
+			}
+		})
+
+		Convey("When parse a float", func() {
+			for s, f := range map[string]float64{"1.5": 1.5, "0.25": 0.25, "1e3": 1000, "2.5E-1": 0.25, "3e+2": 300} {
+				buf.WriteString(s + " ")
+
+				So(lexer.Lex(lval), ShouldEqual, FLOAT)
+				So(lval.float, ShouldEqual, f)
+			}
+
+			Convey("When parse a number followed by an axis", func() {
+				buf.WriteString("1./")
+
+				So(lexer.Lex(lval), ShouldEqual, NUM)
+				So(lexer.Lex(lval), ShouldEqual, AXIS)
+			})
+		})
+
+		Convey("When parse an invalid number", func() {
+			buf.WriteString("0b12")
+
+			So(lexer.Lex(lval), ShouldEqual, ERR)
+			So(lval.err.Error(), ShouldEqual, "invalid number: 0b12")
+		})
+
+		Convey("When parse a single-quoted string", func() {
+			buf.WriteString(`'say "hi"\n'`)
+
+			So(lexer.Lex(lval), ShouldEqual, STR)
+			So(lval.str, ShouldEqual, "say \"hi\"\n")
+		})
+
+		Convey("When parse a regex with flags", func() {
+			buf.WriteString("`^foo`is")
+
+			So(lexer.Lex(lval), ShouldEqual, REGEXP)
+			So(lval.regexp.MatchString("FOO"), ShouldBeTrue)
+			So(lval.regexp.String(), ShouldEqual, "`^foo`is")
+
+			Convey("When parse a regex with unknown flag", func() {
+				buf.WriteString("`foo`x")
+
+				So(lexer.Lex(lval), ShouldEqual, ERR)
+				So(lval.err.Error(), ShouldEqual, "unknown regex flag: x")
+			})
+		})
+
 		Convey("When parse a string", func() {
 			for s, escaped := range strs {
 				buf.WriteString(`"` + escaped + `"`)
@@ -152,9 +221,33 @@ func TestLexer(t *testing.T) {
 			So(lval.str, ShouldEqual, "测试")
 		})
 
+		Convey("When parse a keyword", func() {
+			buf.WriteString("true false null")
+
+			So(lexer.Lex(lval), ShouldEqual, TRUE)
+			So(lexer.Lex(lval), ShouldEqual, FALSE)
+			So(lexer.Lex(lval), ShouldEqual, NULL)
+		})
+
 		Convey("When parse a unexpected rune", func() {
-			buf.WriteString("#")
+			buf.WriteString("\\")
+
+			So(lexer.Lex(lval), ShouldEqual, ERR)
+		})
+
+		Convey("When parse a comment", func() {
+			buf.WriteString("# comment\nfoo # another comment")
+
+			So(lexer.Lex(lval), ShouldEqual, ID)
+			So(lval.str, ShouldEqual, "foo")
+			So(lexer.Lex(lval), ShouldEqual, eof)
+		})
+
+		Convey("When parse a reference", func() {
+			buf.WriteString("$foo_1 $")
 
+			So(lexer.Lex(lval), ShouldEqual, REF)
+			So(lval.str, ShouldEqual, "foo_1")
 			So(lexer.Lex(lval), ShouldEqual, ERR)
 		})
 	})
@@ -0,0 +1,228 @@
+package selector
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Ref references the named query defined in the library, e.g. `$exportedFunc`,
+// which is evaluated from the context node as a step of the paths or the subqueries, e.g. `[ count($defers) > 0 ]`.
+type Ref struct {
+	Name  string `json:"name"`
+	Query Query  `json:"query,omitempty"` // the resolved query
+}
+
+func (r *Ref) String() string {
+	return "$" + r.Name
+}
+
+// Definition is a named query in the library, e.g. `let exportedFunc = // FuncDecl [ @exported ];`
+type Definition struct {
+	Name  string
+	Query Query
+}
+
+func (d *Definition) String() string {
+	return fmt.Sprintf("let %s = %s;", d.Name, d.Query)
+}
+
+// Library holds the named queries loaded from the library files.
+//
+// The library file holds the definitions, imports of other library files and comments.
+//
+//	# the functions could be used by other packages
+//	import "common.astq";
+//
+//	let exportedFunc = // FuncDecl [ @exported ];
+//	let deferFunc = // DeferStmt ..// FuncDecl;
+//	let exportedWithoutDefer = $exportedFunc - $deferFunc;
+//
+// The imported file is resolved relative to the directory of the importing file,
+// and is loaded only once.
+type Library struct {
+	defs    map[string]*Definition
+	loaded  map[string]bool
+	loading []string
+}
+
+func NewLibrary() *Library {
+	return &Library{defs: make(map[string]*Definition), loaded: make(map[string]bool)}
+}
+
+// LoadLibrary loads the library file with its imports.
+func LoadLibrary(filename string) (*Library, error) {
+	lib := NewLibrary()
+
+	if err := lib.Load(filename); err != nil {
+		return nil, err
+	}
+
+	return lib, nil
+}
+
+// Load loads the definitions of the library file with its imports.
+func (lib *Library) Load(filename string) error {
+	path, err := filepath.Abs(filename)
+
+	if err != nil {
+		return err
+	}
+
+	for i, loading := range lib.loading {
+		if loading == path {
+			return fmt.Errorf("import cycle: %s", strings.Join(append(lib.loading[i:], path), " -> "))
+		}
+	}
+
+	if lib.loaded[path] {
+		return nil
+	}
+
+	src, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return err
+	}
+
+	defs, imports, err := ParseLibrary(bytes.NewReader(src))
+
+	if err != nil {
+		return &LibraryError{filename, err}
+	}
+
+	lib.loading = append(lib.loading, path)
+
+	defer func() {
+		lib.loading = lib.loading[:len(lib.loading)-1]
+	}()
+
+	for _, imported := range imports {
+		if !filepath.IsAbs(imported) {
+			imported = filepath.Join(filepath.Dir(path), imported)
+		}
+
+		if err := lib.Load(imported); err != nil {
+			return err
+		}
+	}
+
+	lib.loaded[path] = true
+
+	for _, def := range defs {
+		if err := lib.Define(def.Name, def.Query); err != nil {
+			return &LibraryError{filename, err}
+		}
+	}
+
+	return lib.resolveAll()
+}
+
+// ParseLibrary parses the definitions and imports of the library source.
+func ParseLibrary(r io.Reader) ([]*Definition, []string, error) {
+	lexer := queryNewLexer(r)
+	lexer.library = true
+
+	if queryParse(lexer) == 0 && lexer.Err() == nil {
+		return lexer.defs, lexer.imports, nil
+	}
+
+	return nil, nil, lexer.Err()
+}
+
+// Define adds the named query to the library, the references in the query are resolved lazily.
+func (lib *Library) Define(name string, q Query) error {
+	if _, exists := lib.defs[name]; exists {
+		return fmt.Errorf("duplicated definition: $%s", name)
+	}
+
+	lib.defs[name] = &Definition{name, q}
+
+	return nil
+}
+
+// Lookup returns the named query.
+func (lib *Library) Lookup(name string) (Query, bool) {
+	if def, ok := lib.defs[name]; ok {
+		return def.Query, true
+	}
+
+	return nil, false
+}
+
+// Names returns the names of definitions.
+func (lib *Library) Names() []string {
+	var names []string
+
+	for name := range lib.defs {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// ParseQuery parses the query, and resolves the references with the library definitions.
+func (lib *Library) ParseQuery(s string) (Query, error) {
+	q, err := ParseQuery(s)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := lib.Resolve(q); err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+// Resolve resolves the references in the query with the library definitions.
+func (lib *Library) Resolve(q Query) error {
+	return lib.resolve(q, nil)
+}
+
+func (lib *Library) resolveAll() error {
+	for _, name := range lib.Names() {
+		if err := lib.resolve(lib.defs[name].Query, []string{name}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (lib *Library) resolve(q Query, stack []string) error {
+	var err error
+
+	walkRefs(q, func(ref *Ref) {
+		if err != nil {
+			return
+		}
+
+		for i, name := range stack {
+			if name == ref.Name {
+				err = fmt.Errorf("reference cycle: $%s", strings.Join(append(stack[i:], ref.Name), " -> $"))
+				return
+			}
+		}
+
+		def, ok := lib.defs[ref.Name]
+
+		if !ok {
+			err = fmt.Errorf("undefined reference: $%s", ref.Name)
+			return
+		}
+
+		if err = lib.resolve(def.Query, append(stack[:len(stack):len(stack)], ref.Name)); err == nil {
+			ref.Query = def.Query
+		}
+	})
+
+	return err
+}
@@ -0,0 +1,238 @@
+package selector
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const commonLibrary = `
+# the common definitions
+let funcs = // FuncDecl;
+let methods = $funcs [ @method ];
+`
+
+const mainLibrary = `
+import "common.astq";
+import "sub/defer.astq";
+
+let plainFuncs = $funcs - $methods;
+let withoutDefer = $plainFuncs - $deferFuncs; # functions without defer
+`
+
+const deferLibrary = `
+import "../common.astq";
+
+let deferFuncs = // DeferStmt ..// FuncDecl & $funcs;
+`
+
+func writeLibrary(dir string, files map[string]string) {
+	for name, src := range files {
+		filename := filepath.Join(dir, name)
+
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			panic(err)
+		}
+
+		if err := ioutil.WriteFile(filename, []byte(src), 0644); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func TestLibrary(t *testing.T) {
+	Convey("Given a library source", t, func() {
+		Convey("When parse the library", func() {
+			defs, imports, err := ParseLibrary(strings.NewReader(mainLibrary))
+
+			So(err, ShouldBeNil)
+			So(imports, ShouldResemble, []string{"common.astq", "sub/defer.astq"})
+			So(defs, ShouldHaveLength, 2)
+			So(defs[0].String(), ShouldEqual, "let plainFuncs = $funcs - $methods;")
+			So(defs[1].Query, ShouldResemble, Query{&SetExpr{
+				Path{&Step{Ref: &Ref{Name: "plainFuncs"}}},
+				"-",
+				Path{&Step{Ref: &Ref{Name: "deferFuncs"}}},
+			}})
+		})
+
+		Convey("When parse the library with syntax errors", func() {
+			_, _, err := ParseLibrary(strings.NewReader("let a = // ;\nlet b = // Foo;\nlet = c;"))
+
+			So(err, ShouldNotBeNil)
+
+			errs := SyntaxErrors(err)
+
+			So(errs, ShouldHaveLength, 2)
+			So(errs[0].Line, ShouldEqual, 1)
+			So(errs[1].Line, ShouldEqual, 3)
+		})
+
+		Convey("When parse a query without library", func() {
+			q, err := ParseQuery("$funcs, // Ident")
+
+			So(err, ShouldBeNil)
+			So(q.String(), ShouldEqual, "$funcs, //Ident")
+
+			_, f := parseSource(setSource)
+			_, err = q.Eval(f)
+
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "undefined reference: $funcs")
+		})
+	})
+
+	Convey("Given the library files", t, func() {
+		dir, err := ioutil.TempDir("", "astq")
+
+		So(err, ShouldBeNil)
+
+		defer os.RemoveAll(dir)
+
+		writeLibrary(dir, map[string]string{
+			"common.astq":    commonLibrary,
+			"main.astq":      mainLibrary,
+			"sub/defer.astq": deferLibrary,
+		})
+
+		_, f := parseSource(setSource)
+
+		Convey("When load the library", func() {
+			lib, err := LoadLibrary(filepath.Join(dir, "main.astq"))
+
+			So(err, ShouldBeNil)
+			So(lib.Names(), ShouldResemble, []string{"deferFuncs", "funcs", "methods", "plainFuncs", "withoutDefer"})
+
+			q, err := lib.ParseQuery("$withoutDefer, $methods")
+
+			So(err, ShouldBeNil)
+
+			nodes, err := q.Eval(f)
+
+			So(err, ShouldBeNil)
+			So(funcNames(nodes), ShouldResemble, []string{"Close", "Read"})
+
+			q, err = lib.ParseQuery("$funcs & $deferFuncs")
+
+			So(err, ShouldBeNil)
+
+			nodes, err = q.Eval(f)
+
+			So(err, ShouldBeNil)
+			So(funcNames(nodes), ShouldResemble, []string{"Open", "Write"})
+
+			_, err = lib.ParseQuery("$funcs - $nothing")
+
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "undefined reference: $nothing")
+		})
+
+		Convey("When load the library with import cycle", func() {
+			writeLibrary(dir, map[string]string{
+				"a.astq": `import "b.astq";`,
+				"b.astq": `import "a.astq";`,
+			})
+
+			_, err := LoadLibrary(filepath.Join(dir, "a.astq"))
+
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldStartWith, "import cycle: ")
+			So(err.Error(), ShouldEndWith, "a.astq -> "+filepath.Join(dir, "b.astq")+" -> "+filepath.Join(dir, "a.astq"))
+		})
+
+		Convey("When load the library with reference cycle", func() {
+			writeLibrary(dir, map[string]string{
+				"cycle.astq": `let a = $b; let b = // Ident - $c; let c = $a;`,
+			})
+
+			_, err := LoadLibrary(filepath.Join(dir, "cycle.astq"))
+
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "reference cycle: $a -> $b -> $c -> $a")
+		})
+
+		Convey("When load the library with nested references", func() {
+			writeLibrary(dir, map[string]string{
+				"nested.astq": `
+let defers = // DeferStmt;
+let deferFuncs = // FuncDecl [ $defers ];
+let plainFuncs = // FuncDecl [ count(/:Body * $defers) == 0 ];
+`,
+			})
+
+			lib, err := LoadLibrary(filepath.Join(dir, "nested.astq"))
+
+			So(err, ShouldBeNil)
+
+			for s, names := range map[string][]string{
+				"$deferFuncs":                        {"Open", "Write"},
+				"$plainFuncs":                        {"Close", "Read"},
+				"// FuncDecl [ count($defers) > 0 ]": {"Open", "Write"},
+			} {
+				q, err := lib.ParseQuery(s)
+
+				So(err, ShouldBeNil)
+
+				nodes, err := q.Eval(f)
+
+				So(err, ShouldBeNil)
+				So(funcNames(nodes), ShouldResemble, names)
+			}
+
+			_, err = lib.ParseQuery("// FuncDecl [ count(/ * $nothing) ]")
+
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "undefined reference: $nothing")
+		})
+
+		Convey("When load the library with reference cycle through filters", func() {
+			writeLibrary(dir, map[string]string{
+				"cycle.astq": `let a = // Ident [ $b ]; let b = / * [ count(/ * $a) > 0 ];`,
+			})
+
+			_, err := LoadLibrary(filepath.Join(dir, "cycle.astq"))
+
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "reference cycle: $a -> $b -> $a")
+		})
+
+		Convey("When load the library with duplicated definition", func() {
+			writeLibrary(dir, map[string]string{
+				"dup.astq": `import "common.astq"; let funcs = // Ident;`,
+			})
+
+			_, err := LoadLibrary(filepath.Join(dir, "dup.astq"))
+
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEndWith, "dup.astq: duplicated definition: $funcs")
+		})
+
+		Convey("When load the library with syntax errors", func() {
+			writeLibrary(dir, map[string]string{
+				"bad.astq":    "let a = // ;\nlet b = // Foo;",
+				"import.astq": `import "bad.astq";`,
+			})
+
+			for _, name := range []string{"bad.astq", "import.astq"} {
+				_, err := LoadLibrary(filepath.Join(dir, name))
+
+				So(err, ShouldNotBeNil)
+
+				lerr, ok := err.(*LibraryError)
+
+				So(ok, ShouldBeTrue)
+				So(lerr.Filename, ShouldEqual, filepath.Join(dir, "bad.astq"))
+
+				errs := SyntaxErrors(err)
+
+				So(errs, ShouldHaveLength, 1)
+				So(errs[0].Line, ShouldEqual, 1)
+				So(errs[0].Caret(), ShouldEqual, "let a = // ;\n           ^")
+			}
+		})
+	})
+}
@@ -0,0 +1,160 @@
+package selector
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// EvalOptions controls the evaluation of query across the files.
+type EvalOptions struct {
+	Workers    int // the number of concurrent workers, defaults to GOMAXPROCS
+	MaxResults int // stops after the number of matched nodes, unlimited if zero
+	Params     map[string]interface{}
+}
+
+// FileMatch is the node matched in the file at the index of evaluated files.
+type FileMatch struct {
+	File int
+	Node ast.Node
+}
+
+type fileResult struct {
+	nodes []ast.Node
+	err   error
+}
+
+// EvalFiles evaluates the query against the files in a bounded pool of workers,
+// and calls the function with the matched nodes in the order of files, then the offset of nodes.
+//
+// The function is called from the calling goroutine, the evaluation stops when the function returns an error,
+// the context is done, or the max results are reached.
+func (e *Evaluator) EvalFiles(ctx context.Context, q Query, files []*ast.File, opts EvalOptions, fn func(m FileMatch) error) error {
+	bound, err := bindParams(q, opts.Params)
+
+	if err != nil {
+		return err
+	}
+
+	workers := opts.Workers
+
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	var wg sync.WaitGroup
+
+	defer wg.Wait()
+
+	ctx, cancel := context.WithCancel(ctx)
+
+	defer cancel()
+
+	results := make([]chan fileResult, len(files))
+
+	for i := range results {
+		results[i] = make(chan fileResult, 1)
+	}
+
+	jobs := make(chan int)
+	window := make(chan struct{}, 2*workers) // bounds the files evaluated ahead of the emitting one
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+		defer close(jobs)
+
+		for i := range files {
+			select {
+			case window <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range jobs {
+				nodes, err := e.evalFile(ctx, q, files[i], bound)
+
+				results[i] <- fileResult{nodes, err}
+			}
+		}()
+	}
+
+	matched := 0
+
+	for i := range files {
+		var r fileResult
+
+		select {
+		case r = <-results[i]:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		<-window
+
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if r.err != nil {
+			return fmt.Errorf("%s: %v", e.fileName(files[i], i), r.err)
+		}
+
+		for _, node := range r.nodes {
+			if err := fn(FileMatch{i, node}); err != nil {
+				return err
+			}
+
+			if matched++; opts.MaxResults > 0 && matched >= opts.MaxResults {
+				return nil
+			}
+		}
+	}
+
+	return nil
+}
+
+// evalFile returns the nodes matched in the file, sorted by offset.
+func (e *Evaluator) evalFile(ctx context.Context, q Query, f *ast.File, params map[string]Value) ([]ast.Node, error) {
+	ev := &evaluation{Evaluator: e, tree: NewIndex(f).get(), params: params, ctx: ctx}
+
+	nodes, err := ev.evalQuery(q, f)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return nodes[i].Pos() < nodes[j].Pos()
+	})
+
+	return nodes, nil
+}
+
+func (e *Evaluator) fileName(f *ast.File, i int) string {
+	if e.Fset != nil {
+		if file := e.Fset.File(f.Pos()); file != nil {
+			return file.Name()
+		}
+	}
+
+	return fmt.Sprintf("file #%d", i)
+}
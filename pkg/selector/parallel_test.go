@@ -0,0 +1,130 @@
+package selector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func parseFiles(n int) (*token.FileSet, []*ast.File) {
+	fset := token.NewFileSet()
+
+	var files []*ast.File
+
+	for i := 0; i < n; i++ {
+		f, err := parser.ParseFile(fset, fmt.Sprintf("test%d.go", i), evalSource, parser.ParseComments)
+
+		if err != nil {
+			panic(err)
+		}
+
+		files = append(files, f)
+	}
+
+	return fset, files
+}
+
+func TestEvalFiles(t *testing.T) {
+	Convey("Given the parsed files", t, func() {
+		fset, files := parseFiles(20)
+
+		e := NewEvaluator(fset)
+
+		q, err := ParseQuery("// Ident, // CallExpr")
+
+		So(err, ShouldBeNil)
+
+		var expected []FileMatch
+
+		for i, f := range files {
+			nodes, err := e.Eval(q, f)
+
+			So(err, ShouldBeNil)
+
+			for _, node := range nodes {
+				expected = append(expected, FileMatch{i, node})
+			}
+		}
+
+		Convey("When evaluate the query in the workers", func() {
+			for _, workers := range []int{0, 1, 3, 64} {
+				var matches []FileMatch
+
+				err := e.EvalFiles(context.Background(), q, files, EvalOptions{Workers: workers}, func(m FileMatch) error {
+					matches = append(matches, m)
+
+					return nil
+				})
+
+				So(err, ShouldBeNil)
+				So(matches, ShouldHaveLength, len(expected))
+
+				for i := 1; i < len(matches); i++ {
+					prev, next := matches[i-1], matches[i]
+
+					So(prev.File < next.File || prev.File == next.File && prev.Node.Pos() <= next.Node.Pos(), ShouldBeTrue)
+				}
+			}
+		})
+
+		Convey("When stop after the max results", func() {
+			var matches []FileMatch
+
+			err := e.EvalFiles(context.Background(), q, files, EvalOptions{Workers: 4, MaxResults: 5}, func(m FileMatch) error {
+				matches = append(matches, m)
+
+				return nil
+			})
+
+			So(err, ShouldBeNil)
+			So(matches, ShouldHaveLength, 5)
+			So(matches[0].File, ShouldEqual, 0)
+		})
+
+		Convey("When the context is canceled", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+
+			matched := 0
+
+			err := e.EvalFiles(ctx, q, files, EvalOptions{Workers: 4}, func(m FileMatch) error {
+				if matched++; matched == 3 {
+					cancel()
+				}
+
+				return nil
+			})
+
+			So(err, ShouldEqual, context.Canceled)
+			So(matched, ShouldBeLessThan, len(expected))
+		})
+
+		Convey("When the callback returns an error", func() {
+			stop := errors.New("stop")
+
+			err := e.EvalFiles(context.Background(), q, files, EvalOptions{}, func(m FileMatch) error {
+				return stop
+			})
+
+			So(err, ShouldEqual, stop)
+		})
+
+		Convey("When fail to evaluate a file", func() {
+			q, err := ParseQuery("// FuncDecl [ foo() ]")
+
+			So(err, ShouldBeNil)
+
+			err = e.EvalFiles(context.Background(), q, files, EvalOptions{}, func(m FileMatch) error {
+				return nil
+			})
+
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldStartWith, "test0.go: ")
+		})
+	})
+}
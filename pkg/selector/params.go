@@ -0,0 +1,80 @@
+package selector
+
+import (
+	"fmt"
+	"go/ast"
+	"reflect"
+	"regexp"
+	"sort"
+)
+
+// Params returns the sorted names of the query parameters.
+func (q Query) Params() []string {
+	seen := make(map[string]bool)
+
+	var names []string
+
+	walkQuery(q, func(expr Expr) {
+		if param, ok := expr.(QueryParam); ok && !seen[string(param)] {
+			seen[string(param)] = true
+			names = append(names, string(param))
+		}
+	})
+
+	sort.Strings(names)
+
+	return names
+}
+
+func bindParams(q Query, params map[string]interface{}) (map[string]Value, error) {
+	bound := make(map[string]Value)
+
+	for _, name := range q.Params() {
+		param, ok := params[name]
+
+		if !ok {
+			return nil, fmt.Errorf("unbound query parameter: %s", QueryParam(name))
+		}
+
+		v, err := valueOf(param)
+
+		if err != nil {
+			return nil, fmt.Errorf("query parameter %s: %v", QueryParam(name), err)
+		}
+
+		bound[name] = v
+	}
+
+	return bound, nil
+}
+
+// valueOf converts the Go value to the query value.
+func valueOf(x interface{}) (Value, error) {
+	switch x := x.(type) {
+	case nil:
+		return Null{}, nil
+	case Value:
+		return x, nil
+	case bool:
+		return Bool(x), nil
+	case string:
+		return Str(x), nil
+	case *regexp.Regexp:
+		return &Regexp{x}, nil
+	case []ast.Node:
+		return NodeSet(x), nil
+	case ast.Node:
+		return NodeSet{x}, nil
+	}
+
+	switch v := reflect.ValueOf(x); v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return Num(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return Num(v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return Float(v.Float()), nil
+	}
+
+	return nil, fmt.Errorf("unsupported value type %T", x)
+}
@@ -0,0 +1,75 @@
+package selector
+
+import (
+	"go/ast"
+	"regexp"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestParams(t *testing.T) {
+	Convey("Given a parsed file", t, func() {
+		_, f := parseSource(evalSource)
+
+		Convey("When evaluate a query with bound parameters", func() {
+			q, err := ParseQuery("// FuncDecl [ @name == {name} ]")
+
+			So(err, ShouldBeNil)
+
+			for _, name := range []string{"Foo", "Bar"} {
+				nodes, err := q.EvalWithParams(f, map[string]interface{}{"name": name})
+
+				So(err, ShouldBeNil)
+				So(nodes, ShouldHaveLength, 1)
+				So(nodes[0].(*ast.FuncDecl).Name.Name, ShouldEqual, name)
+			}
+		})
+
+		Convey("When evaluate a query with parameters of different types", func() {
+			var params = map[string]interface{}{
+				"n":    2,
+				"u":    uint8(1),
+				"b":    true,
+				"re":   regexp.MustCompile("^F"),
+				"none": nil,
+				"node": f.Decls[1],
+			}
+
+			var queries = map[string]int{
+				"// BasicLit [ @value == {n} || @value == {u} ]": 2,
+				"// FuncDecl [ @exported == {b} ]":               2,
+				"// FuncDecl [ @name =~ {re} ]":                  1,
+				"// FuncDecl [ @tag == {none} ]":                 2,
+				"// * [ in({node}) ]":                            1,
+			}
+
+			for s, expected := range queries {
+				q, err := ParseQuery(s)
+
+				So(err, ShouldBeNil)
+
+				nodes, err := q.EvalWithParams(f, params)
+
+				So(err, ShouldBeNil)
+				So(nodes, ShouldHaveLength, expected)
+			}
+		})
+
+		Convey("When evaluate a query with unbound parameters", func() {
+			q, err := ParseQuery("// FuncDecl [ @name == {name} ]")
+
+			So(err, ShouldBeNil)
+
+			_, err = q.Eval(f)
+
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "unbound query parameter: {name}")
+
+			_, err = q.EvalWithParams(f, map[string]interface{}{"name": struct{}{}})
+
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "query parameter {name}: unsupported value type struct {}")
+		})
+	})
+}
@@ -11,12 +11,23 @@ func ParseQuery(s string) (Query, error) {
 	return Parse(bytes.NewBuffer([]byte(s)))
 }
 
+// Parse parses the query, the parser recovers from the syntax errors,
+// and reports all of them as *SyntaxError in a multierror.
 func Parse(r io.Reader) (Query, error) {
 	lexer := queryNewLexer(r)
 
-	if queryParse(lexer) == 0 {
+	if queryParse(lexer) == 0 && lexer.Err() == nil {
 		return lexer.Result(), nil
 	}
 
 	return nil, lexer.Err()
 }
+
+// newPredicate returns a step holding the filter, or the positional predicate of a number, e.g. `[1]` or `[-1]`.
+func newPredicate(filter Expr) *Step {
+	if n, ok := filter.(Num); ok {
+		return &Step{Slice: &Slice{Start: int(n), End: int(n)}}
+	}
+
+	return &Step{Filter: filter}
+}
@@ -63,6 +63,8 @@ var stepWithAxis = map[string]*Step{
 	"/:\"hello world\" bar": &Step{Axis: &Axis{Dir: "/", Type: "hello world"}, Match: "bar"},
 	"/:foo bar [@name]":     &Step{Axis: &Axis{Dir: "/", Type: "foo"}, Match: "bar", Filter: &WithAttr{"name"}},
 	"/:foo bar ![@name]":    &Step{Axis: &Axis{Dir: "/", Type: "foo"}, Match: "bar", Result: true, Filter: &WithAttr{"name"}},
+	"/:foo bar !":           &Step{Axis: &Axis{Dir: "/", Type: "foo"}, Match: "bar", Result: true},
+	"bar !":                 &Step{Match: "bar", Result: true},
 }
 
 func TestStepWithAxis(t *testing.T) {
@@ -167,12 +169,15 @@ func TestArithmethicalOps(t *testing.T) {
 	Convey("Given a parser", t, func() {
 		Convey("When parse query with arithmethical operator", func() {
 			var exprs = map[string]Expr{
-				"* [1 + 5]": &Binary{Num(1), "+", Num(5)},
-				"* [1 - 5]": &Binary{Num(1), "-", Num(5)},
-				"* [8 * 2]": &Binary{Num(8), "*", Num(2)},
-				"* [8 / 2]": &Binary{Num(8), "/", Num(2)},
-				"* [8 % 2]": &Binary{Num(8), "%", Num(2)},
-				"* [8 ^ 2]": &Binary{Num(8), "^", Num(2)},
+				"* [1 + 5]":        &Binary{Num(1), "+", Num(5)},
+				"* [1 - 5]":        &Binary{Num(1), "-", Num(5)},
+				"* [8 * 2]":        &Binary{Num(8), "*", Num(2)},
+				"* [8 / 2]":        &Binary{Num(8), "/", Num(2)},
+				"* [8 % 2]":        &Binary{Num(8), "%", Num(2)},
+				"* [8 ^ 2]":        &Binary{Num(8), "^", Num(2)},
+				"* [1.5 - -2]":     &Binary{Float(1.5), "-", Num(-2)},
+				"* [-0.5 * 1e+21]": &Binary{Float(-0.5), "*", Float(1e21)},
+				"* [2.0 / 4]":      &Binary{Float(2), "/", Num(4)},
 			}
 
 			for q, expected := range exprs {
@@ -205,3 +210,114 @@ func TestFuncCall(t *testing.T) {
 		})
 	})
 }
+
+func TestSubQuery(t *testing.T) {
+	Convey("Given a parser", t, func() {
+		Convey("When parse query with sub query", func() {
+			var exprs = map[string]Expr{
+				"* [.//GoStmt]": Path{&Step{Axis: &Axis{Dir: ".//"}, Match: "GoStmt"}},
+				"* [count(/:Body BlockStmt /ReturnStmt) > 1]": &Binary{
+					&FuncCall{"count", []Expr{Path{
+						&Step{Axis: &Axis{Dir: "/", Type: "Body"}, Match: "BlockStmt"},
+						&Step{Axis: &Axis{Dir: "/"}, Match: "ReturnStmt"},
+					}}},
+					">",
+					Num(1),
+				},
+				"* [8 / 2]": &Binary{Num(8), "/", Num(2)},
+			}
+
+			for q, expected := range exprs {
+				parsed, err := ParseQuery(q)
+
+				So(err, ShouldBeNil)
+				So(parsed, ShouldResemble, Query{Path{&Step{Match: "*", Filter: expected}}})
+				So(parsed.String(), ShouldEqual, q)
+			}
+		})
+	})
+}
+
+func TestQueryParam(t *testing.T) {
+	Convey("Given a parser", t, func() {
+		Convey("When parse query with query parameter", func() {
+			q := "//FuncDecl [@name == {name} && @exported != {exported}]"
+			parsed, err := ParseQuery(q)
+
+			So(err, ShouldBeNil)
+			So(parsed, ShouldResemble, Query{Path{&Step{
+				Axis:  &Axis{Dir: "//"},
+				Match: "FuncDecl",
+				Filter: &Binary{
+					&Binary{&WithAttr{"name"}, "==", QueryParam("name")},
+					"&&",
+					&Binary{&WithAttr{"exported"}, "!=", QueryParam("exported")},
+				},
+			}}})
+			So(parsed.String(), ShouldEqual, q)
+			So(parsed.Params(), ShouldResemble, []string{"exported", "name"})
+		})
+	})
+}
+
+func TestSyntaxError(t *testing.T) {
+	Convey("Given a parser", t, func() {
+		Convey("When parse query with syntax error", func() {
+			_, err := ParseQuery("// FuncDecl [ @name == ]")
+
+			So(err, ShouldNotBeNil)
+
+			errs := SyntaxErrors(err)
+
+			So(errs, ShouldHaveLength, 1)
+			So(errs[0].Offset, ShouldEqual, 23)
+			So(errs[0].Line, ShouldEqual, 1)
+			So(errs[0].Column, ShouldEqual, 24)
+			So(errs[0].Token, ShouldEqual, "]")
+			So(errs[0].Expected, ShouldContain, "identifier")
+			So(errs[0].Expected, ShouldContain, "'('")
+			So(errs[0].Expected, ShouldNotContain, "']'")
+			So(errs[0].Error(), ShouldStartWith, `1:24: syntax error: unexpected "]", expecting identifier, string, number`)
+			So(errs[0].Caret(), ShouldEqual, "// FuncDecl [ @name == ]\n                       ^")
+		})
+
+		Convey("When parse incomplete query", func() {
+			errs := SyntaxErrors(func() error { _, err := ParseQuery("/"); return err }())
+
+			So(errs, ShouldHaveLength, 1)
+			So(errs[0].Token, ShouldBeEmpty)
+			So(errs[0].Expected, ShouldResemble, []string{"identifier", "string", "not", "':'", "'*'"})
+			So(errs[0].Error(), ShouldEqual, "1:2: syntax error: unexpected EOF, expecting identifier, string, not, ':' or '*'")
+		})
+
+		Convey("When parse query with lexical error", func() {
+			_, err := ParseQuery("// Foo [ \"\\xzz\" ]")
+
+			errs := SyntaxErrors(err)
+
+			So(errs, ShouldHaveLength, 1)
+			So(errs[0].Err, ShouldNotBeNil)
+			So(errs[0].Error(), ShouldEqual, "1:10: invalid hex char: 'z'")
+			So(errs[0].Caret(), ShouldEqual, "// Foo [ \"\\xzz\" ]\n         ^^^^^^")
+
+			_, err = ParseQuery("// Foo [ `(` ]")
+
+			So(err, ShouldNotBeNil)
+			So(SyntaxErrors(err)[0].Error(), ShouldEqual, "1:10: error parsing regexp: missing closing ): `(`")
+		})
+
+		Convey("When parse query with multiple errors", func() {
+			_, err := ParseQuery("/Foo [ 1 + ] /Bar,\n\t/Baz [ 3 - ] / Qux / [ ! ]")
+
+			errs := SyntaxErrors(err)
+
+			So(errs, ShouldHaveLength, 3)
+			So(errs[0].Line, ShouldEqual, 1)
+			So(errs[0].Column, ShouldEqual, 12)
+			So(errs[1].Line, ShouldEqual, 2)
+			So(errs[1].Column, ShouldEqual, 13)
+			So(errs[1].Caret(), ShouldEqual, "\t/Baz [ 3 - ] / Qux / [ ! ]\n\t           ^")
+			So(errs[2].Token, ShouldEqual, "[")
+		})
+	})
+}
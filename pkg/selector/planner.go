@@ -0,0 +1,100 @@
+package selector
+
+import (
+	"fmt"
+	"go/ast"
+	"sync"
+)
+
+// Index is the index of a syntax tree with the parent links and the lists of nodes by type name,
+// which is built lazily and shared by the evaluations on the same file.
+//
+// Each evaluation of Eval or EvalFiles indexes the file too, the lists of nodes are collected
+// on the first lookup of each type name, so a query only pays for the node types it looks up.
+type Index struct {
+	root ast.Node
+	once sync.Once
+	tree *tree
+}
+
+// NewIndex returns the index of the syntax tree rooted at root, which is built on the first evaluation.
+func NewIndex(root ast.Node) *Index {
+	return &Index{root: root}
+}
+
+func (idx *Index) get() *tree {
+	idx.once.Do(func() {
+		idx.tree = newTree(idx.root)
+	})
+
+	return idx.tree
+}
+
+// EvalIndex evaluates the query against the indexed syntax tree with the bound query parameters.
+func (e *Evaluator) EvalIndex(q Query, idx *Index, params map[string]interface{}) ([]ast.Node, error) {
+	return e.EvalIndexFrom(q, idx, idx.root, params)
+}
+
+// EvalIndexFrom evaluates the query from the context node of the indexed syntax tree with the bound query parameters,
+// the relative axes may reach the parent and sibling nodes of the context node, e.g. `../` or `+/`.
+func (e *Evaluator) EvalIndexFrom(q Query, idx *Index, node ast.Node, params map[string]interface{}) ([]ast.Node, error) {
+	bound, err := bindParams(q, params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	t := idx.get()
+
+	if _, ok := t.info[node]; !ok {
+		return nil, fmt.Errorf("%s node is not in the indexed syntax tree", typeName(node))
+	}
+
+	ev := &evaluation{Evaluator: e, tree: t, params: bound}
+
+	return ev.evalQuery(q, node)
+}
+
+// lookup is the plan of step which looks up the descendant nodes of the type in the index,
+// instead of scanning all the descendant nodes through the axis.
+type lookup struct {
+	match string
+	self  bool
+}
+
+// plan returns the lookup of the descendant step which matches a node type, or nil if the step scans the axis.
+func (e *Evaluator) plan(step *Step) *lookup {
+	if e.noPlan || step.Axis == nil || step.Ref != nil || step.Not != nil || step.Match == "*" {
+		return nil
+	}
+
+	switch step.Axis.Dir {
+	case "//":
+		return &lookup{step.Match, false}
+	case ".//":
+		return &lookup{step.Match, true}
+	default:
+		return nil
+	}
+}
+
+// candidates returns the candidate nodes of step from the node, and whether they are looked up in the index.
+func (e *evaluation) candidates(step *Step, node ast.Node) ([]ast.Node, bool, error) {
+	if step.Ref != nil {
+		if step.Ref.Query == nil {
+			return nil, false, fmt.Errorf("undefined reference: %s", step.Ref)
+		}
+
+		nodes, err := e.evalQuery(step.Ref.Query, node)
+
+		return nodes, false, err
+	}
+
+	if l := e.plan(step); l != nil {
+		return e.reachedBy(step.Axis, e.tree.typedDescendants(node, l.match, l.self)), true, nil
+	}
+
+	nodes, err := e.axis(step.Axis, node)
+
+	return nodes, false, err
+}
@@ -0,0 +1,191 @@
+package selector
+
+import (
+	"context"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPlanner(t *testing.T) {
+	Convey("Given a parsed file", t, func() {
+		_, f := parseSource(evalSource)
+
+		Convey("When plan the steps", func() {
+			var steps = map[string]*lookup{
+				"// CallExpr":        {"CallExpr", false},
+				".//:Body BlockStmt": {"BlockStmt", true},
+				"// *":               nil,
+				"/ CallExpr":         nil,
+				"CallExpr":           nil,
+				"// not(CallExpr)":   nil,
+				"$calls":             nil,
+			}
+
+			for s, expected := range steps {
+				q, err := ParseQuery(s)
+
+				So(err, ShouldBeNil)
+				So(NewEvaluator(nil).plan(q[0].(Path)[0]), ShouldResemble, expected)
+			}
+		})
+
+		Convey("When evaluate the queries with index lookups", func() {
+			var queries = []string{
+				"// CallExpr",
+				"// FuncDecl // BasicLit",
+				".// File",
+				"// FuncDecl .// FuncDecl",
+				"// FuncDecl //:Results Field",
+				"// CallExpr [ // Ident [ @name == \"Foo\" ] ]",
+				"// FuncDecl ! // Ident [2]",
+				"// Ident - // CallExpr // Ident",
+			}
+
+			idx := NewIndex(f)
+
+			for _, s := range queries {
+				q, err := ParseQuery(s)
+
+				So(err, ShouldBeNil)
+
+				scanned, err := (&Evaluator{noPlan: true}).Eval(q, f)
+
+				So(err, ShouldBeNil)
+				So(scanned, ShouldNotBeEmpty)
+
+				planned, err := NewEvaluator(nil).EvalIndex(q, idx, nil)
+
+				So(err, ShouldBeNil)
+				So(planned, ShouldResemble, scanned)
+			}
+		})
+
+		Convey("When evaluate the queries from the context node in index", func() {
+			idx := NewIndex(f)
+			foo := f.Decls[1].(*ast.FuncDecl)
+
+			var queries = map[string][]string{
+				"../ *":                       {"File"},
+				"-/ *":                        {"GenDecl"},
+				"+/ FuncDecl":                 {"FuncDecl"},
+				"<// Ident":                   {"Ident"},
+				"// Ident [ @name == \"a\" ]": {"Ident", "Ident"},
+			}
+
+			for s, expected := range queries {
+				q, err := ParseQuery(s)
+
+				So(err, ShouldBeNil)
+
+				nodes, err := NewEvaluator(nil).EvalIndexFrom(q, idx, foo, nil)
+
+				So(err, ShouldBeNil)
+				So(typeNames(nodes), ShouldResemble, expected)
+			}
+
+			q, err := ParseQuery("../ *")
+
+			So(err, ShouldBeNil)
+
+			_, err = NewEvaluator(nil).EvalIndexFrom(q, idx, &ast.Ident{Name: "x"}, nil)
+
+			So(err, ShouldNotBeNil)
+			So(err.Error(), ShouldEqual, "Ident node is not in the indexed syntax tree")
+		})
+	})
+}
+
+func parsePackage(b *testing.B, dir string) (files []*ast.File) {
+	filenames, err := filepath.Glob(filepath.Join(runtime.GOROOT(), "src", dir, "*.go"))
+
+	if err != nil || len(filenames) == 0 {
+		b.Skipf("missing the source of package %s", dir)
+	}
+
+	fset := token.NewFileSet()
+
+	for _, filename := range filenames {
+		f, err := parser.ParseFile(fset, filename, nil, parser.ParseComments)
+
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		files = append(files, f)
+	}
+
+	return
+}
+
+var benchmarkQueries = map[string]string{
+	"rare":   "// GoStmt",
+	"nested": "// FuncDecl // ReturnStmt",
+	"filter": "// CallExpr [ /:Fun SelectorExpr [ @name == \"Errorf\" ] ]",
+}
+
+// benchmarkEval evaluates the queries against the files through EvalFiles, which indexes each file for every evaluation.
+func benchmarkEval(b *testing.B, e *Evaluator) {
+	files := parsePackage(b, "go/types")
+
+	for name, s := range benchmarkQueries {
+		q, err := ParseQuery(s)
+
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				err := e.EvalFiles(context.Background(), q, files, EvalOptions{Workers: 1}, func(m FileMatch) error {
+					return nil
+				})
+
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkEvalScan scans all the descendant nodes of each file.
+func BenchmarkEvalScan(b *testing.B) { benchmarkEval(b, &Evaluator{noPlan: true}) }
+
+// BenchmarkEvalIndex looks up the descendant nodes of each file in the index.
+func BenchmarkEvalIndex(b *testing.B) { benchmarkEval(b, NewEvaluator(nil)) }
+
+// BenchmarkEvalSharedIndex looks up the nodes in the indexes shared by the evaluations, like the REPL.
+func BenchmarkEvalSharedIndex(b *testing.B) {
+	files := parsePackage(b, "go/types")
+	e := NewEvaluator(nil)
+
+	for name, s := range benchmarkQueries {
+		q, err := ParseQuery(s)
+
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.Run(name, func(b *testing.B) {
+			indexes := make([]*Index, len(files))
+
+			for i, f := range files {
+				indexes[i] = NewIndex(f)
+			}
+
+			for i := 0; i < b.N; i++ {
+				for _, idx := range indexes {
+					if _, err := e.EvalIndex(q, idx, nil); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
@@ -9,38 +9,49 @@ import __yyfmt__ "fmt"
 
 //line query.y:5
 type querySymType struct {
-	yys    int
-	query  Query
-	path   Path
-	axis   *Axis
-	step   *Step
-	expr   Expr
-	args   []Expr
-	regexp *Regexp
-	err    error
-	str    string
-	num    int64
+	yys      int
+	query    Query
+	pathExpr PathExpr
+	path     Path
+	axis     *Axis
+	step     *Step
+	slice    *Slice
+	expr     Expr
+	args     []Expr
+	regexp   *Regexp
+	err      error
+	str      string
+	num      int64
+	float    float64
 }
 
-const REGEXP = 57346
-const ERR = 57347
-const ID = 57348
-const STR = 57349
-const TRUE = 57350
-const FALSE = 57351
-const NULL = 57352
-const LSHIFT = 57353
-const RSHIFT = 57354
-const AND = 57355
-const OR = 57356
-const EQ = 57357
-const NE = 57358
-const LTE = 57359
-const GTE = 57360
-const MATCH = 57361
-const NONMATCH = 57362
-const ELSE_OR = 57363
-const NUM = 57364
+const LIBRARY = 57346
+const LET = 57347
+const IMPORT = 57348
+const REGEXP = 57349
+const ERR = 57350
+const ID = 57351
+const STR = 57352
+const TRUE = 57353
+const FALSE = 57354
+const NULL = 57355
+const AXIS = 57356
+const NOT = 57357
+const REF = 57358
+const LSHIFT = 57359
+const RSHIFT = 57360
+const AND = 57361
+const OR = 57362
+const EQ = 57363
+const NE = 57364
+const LTE = 57365
+const GTE = 57366
+const MATCH = 57367
+const NONMATCH = 57368
+const ELSE_OR = 57369
+const NUM = 57370
+const FLOAT = 57371
+const SUBQUERY = 57372
 
 var queryToknames = [...]string{
 	"$end",
@@ -50,10 +61,17 @@ var queryToknames = [...]string{
 	"']'",
 	"'('",
 	"')'",
+	"'{'",
+	"'}'",
 	"':'",
 	"'@'",
 	"'.'",
 	"','",
+	"';'",
+	"'='",
+	"LIBRARY",
+	"LET",
+	"IMPORT",
 	"REGEXP",
 	"ERR",
 	"ID",
@@ -61,6 +79,9 @@ var queryToknames = [...]string{
 	"TRUE",
 	"FALSE",
 	"NULL",
+	"AXIS",
+	"NOT",
+	"REF",
 	"'+'",
 	"'-'",
 	"'*'",
@@ -86,147 +107,199 @@ var queryToknames = [...]string{
 	"NONMATCH",
 	"ELSE_OR",
 	"NUM",
-	"'{'",
-	"'}'",
+	"FLOAT",
+	"SUBQUERY",
 }
+
 var queryStatenames = [...]string{}
 
 const queryEofCode = 1
 const queryErrCode = 2
 const queryInitialStackSize = 16
 
-//line query.y:294
+//line query.y:470
 
 //line yacctab:1
-var queryExca = [...]int{
+var queryExca = [...]int8{
 	-1, 1,
 	1, -1,
 	-2, 0,
+	-1, 25,
+	1, 2,
+	-2, 0,
+	-1, 34,
+	10, 40,
+	-2, 0,
+	-1, 52,
+	10, 41,
+	-2, 106,
+	-1, 93,
+	10, 42,
+	-2, 108,
 }
 
 const queryPrivate = 57344
 
-const queryLast = 150
-
-var queryAct = [...]int{
-
-	36, 37, 43, 42, 40, 38, 20, 117, 79, 80,
-	56, 81, 82, 50, 72, 106, 54, 22, 49, 52,
-	57, 58, 59, 92, 91, 73, 76, 75, 35, 22,
-	60, 39, 41, 70, 87, 88, 89, 90, 85, 86,
-	61, 69, 28, 7, 8, 77, 83, 53, 51, 4,
-	9, 113, 21, 19, 27, 56, 68, 104, 50, 67,
-	66, 54, 65, 49, 52, 57, 58, 59, 105, 64,
-	33, 3, 32, 31, 107, 108, 30, 41, 29, 26,
-	109, 101, 102, 110, 19, 62, 63, 103, 111, 112,
-	34, 120, 53, 51, 56, 121, 114, 50, 18, 119,
-	54, 116, 49, 52, 57, 58, 59, 25, 12, 118,
-	100, 71, 7, 8, 22, 1, 55, 14, 13, 9,
-	11, 122, 123, 17, 16, 93, 15, 84, 78, 74,
-	24, 53, 51, 94, 95, 96, 97, 99, 98, 5,
-	10, 115, 48, 47, 46, 45, 23, 44, 6, 2,
+const queryLast = 334
+
+var queryAct = [...]uint8{
+	50, 54, 79, 12, 30, 2, 60, 59, 57, 55,
+	49, 12, 12, 75, 145, 94, 93, 94, 160, 99,
+	98, 95, 131, 27, 141, 7, 74, 12, 69, 12,
+	12, 68, 96, 26, 104, 105, 48, 106, 107, 72,
+	82, 67, 70, 76, 77, 78, 23, 140, 81, 88,
+	102, 34, 22, 46, 127, 128, 9, 56, 58, 119,
+	120, 121, 122, 124, 123, 6, 100, 108, 137, 34,
+	34, 19, 20, 101, 71, 130, 23, 17, 14, 13,
+	125, 21, 22, 4, 133, 23, 132, 81, 134, 136,
+	47, 22, 45, 29, 129, 35, 87, 142, 143, 19,
+	20, 139, 83, 31, 23, 17, 14, 144, 39, 21,
+	22, 138, 146, 26, 19, 20, 84, 147, 148, 149,
+	17, 85, 86, 89, 21, 150, 44, 152, 24, 164,
+	51, 24, 161, 90, 74, 38, 69, 10, 156, 68,
+	12, 42, 43, 158, 153, 34, 28, 72, 135, 67,
+	70, 76, 77, 78, 23, 162, 81, 157, 53, 155,
+	22, 163, 154, 165, 166, 56, 58, 126, 36, 34,
+	74, 159, 69, 92, 91, 68, 41, 25, 1, 73,
+	118, 52, 71, 72, 109, 67, 70, 76, 77, 78,
+	23, 103, 81, 97, 102, 16, 22, 37, 18, 151,
+	65, 56, 58, 64, 63, 62, 74, 61, 69, 32,
+	33, 68, 11, 80, 15, 66, 8, 101, 71, 72,
+	0, 67, 70, 76, 77, 78, 23, 0, 81, 0,
+	102, 0, 22, 0, 0, 0, 0, 0, 58, 0,
+	0, 0, 74, 0, 69, 0, 0, 68, 0, 0,
+	0, 0, 0, 101, 71, 72, 0, 67, 70, 76,
+	77, 78, 23, 0, 81, 0, 102, 0, 22, 117,
+	116, 0, 5, 0, 0, 0, 9, 0, 0, 0,
+	112, 113, 114, 115, 110, 111, 3, 0, 0, 101,
+	71, 19, 20, 0, 0, 5, 23, 17, 14, 9,
+	0, 21, 22, 40, 0, 0, 0, 9, 0, 0,
+	0, 0, 0, 0, 19, 20, 0, 0, 0, 23,
+	17, 14, 19, 20, 21, 22, 0, 23, 17, 14,
+	0, 0, 21, 22,
 }
-var queryPact = [...]int{
-
-	98, -1000, 87, 98, -1000, 25, 29, -1000, -1000, -1000,
-	99, 57, 32, 56, 54, 51, 50, 48, 98, -1000,
-	-1000, 110, 4, 13, -1000, 71, -1000, 47, 40, 38,
-	37, 34, 19, 11, 98, -1000, 106, -17, -8, 49,
-	-21, 88, -2, 114, -1000, -1000, -1000, -1000, -1000, 104,
-	67, 73, -1000, -1000, -1000, -1000, 4, -1000, -1000, -1000,
-	-1000, 110, -1000, -1000, -1000, -7, -1000, -1000, -1000, -1000,
-	-1000, -1000, 4, 4, 49, -1000, -1000, -1000, 88, -1000,
-	-1000, -1000, -1000, -1000, 88, 77, 39, -1000, -1000, -1000,
-	-1000, -1000, -1000, 88, -1000, -1000, -1000, -1000, -1000, -1000,
-	4, -1000, -1000, -38, 102, -1000, -1000, 91, -1000, -1000,
-	-1000, -1000, -1000, -1000, -1000, 84, -1000, -1000, -1000, 4,
-	-1000, 4, -1000, -1000,
+
+var queryPact = [...]int16{
+	270, -32768, 118, -32768, 3, -32768, -16, -32768, 78, 50,
+	-32768, 66, -32768, -32768, -32768, 93, -32768, 162, 125, -32768,
+	-32768, -32768, -32768, -32768, 301, 124, 50, 50, -32768, 83,
+	-32768, 165, -32768, -32768, 128, 65, 93, -32768, 100, 3,
+	-32768, -32768, 75, 27, 109, -16, -32768, -32768, -32768, 123,
+	169, 168, -32768, -37, -20, -25, 200, -5, 236, 234,
+	30, -32768, -32768, -32768, -32768, -32768, 59, 161, 33, 73,
+	-32768, -32768, -32768, -32768, 20, -32768, -32768, -32768, -32768, -32768,
+	-32768, 47, -32768, 165, 141, -32768, -32768, 53, 97, -32768,
+	-6, -32768, -32768, -32768, -32768, 164, 164, 200, -32768, -32768,
+	-32768, -32768, -39, 236, -32768, -32768, -32768, -32768, -32768, 236,
+	236, 236, -32768, -32768, -32768, -32768, -32768, -32768, 236, -32768,
+	-32768, -32768, -32768, -32768, -32768, -32768, 164, -32768, -32768, 135,
+	155, 152, -32768, 165, -32768, -32768, 150, 293, -32768, 166,
+	-32768, -35, 122, -32768, -32768, -32768, -32768, -32768, -32768, -32768,
+	-32768, 148, -32768, -32768, -32768, -32768, -32768, -32768, 115, -32768,
+	-32768, 164, -32768, 164, -32768, -32768, -32768,
 }
-var queryPgo = [...]int{
 
-	0, 149, 71, 148, 49, 6, 147, 145, 144, 143,
-	142, 0, 1, 5, 4, 3, 2, 141, 140, 130,
-	139, 129, 128, 127, 125, 116, 115,
+var queryPgo = [...]uint8{
+	0, 5, 83, 65, 25, 216, 215, 214, 137, 2,
+	213, 13, 212, 79, 4, 210, 10, 209, 207, 205,
+	204, 203, 200, 0, 1, 9, 8, 7, 6, 199,
+	198, 197, 195, 193, 191, 184, 180, 179, 178, 177,
+	176,
 }
-var queryR1 = [...]int{
-
-	0, 26, 1, 1, 2, 2, 4, 4, 4, 4,
-	4, 4, 20, 20, 20, 5, 3, 3, 18, 18,
-	18, 18, 18, 18, 18, 18, 18, 18, 18, 18,
-	18, 18, 19, 19, 11, 11, 11, 12, 12, 12,
-	21, 21, 13, 13, 13, 22, 22, 22, 22, 14,
-	14, 14, 14, 23, 23, 23, 23, 23, 23, 15,
-	15, 24, 24, 24, 24, 24, 24, 16, 16, 16,
-	16, 16, 6, 17, 17, 17, 7, 7, 8, 9,
-	9, 9, 9, 25, 25, 25, 10,
+
+var queryR1 = [...]int8{
+	0, 38, 38, 39, 39, 40, 40, 40, 1, 1,
+	1, 1, 2, 2, 3, 3, 4, 4, 5, 5,
+	8, 8, 8, 8, 8, 12, 12, 9, 9, 9,
+	9, 13, 13, 13, 32, 32, 32, 14, 14, 15,
+	16, 16, 16, 17, 17, 7, 7, 30, 30, 31,
+	31, 23, 23, 23, 24, 24, 24, 33, 33, 25,
+	25, 25, 34, 34, 34, 34, 26, 26, 26, 26,
+	35, 35, 35, 35, 35, 35, 27, 27, 36, 36,
+	36, 36, 36, 36, 28, 28, 28, 28, 28, 28,
+	6, 6, 11, 11, 10, 10, 10, 10, 18, 29,
+	29, 29, 19, 19, 20, 21, 21, 21, 21, 21,
+	21, 21, 37, 37, 37, 22, 22,
 }
-var queryR2 = [...]int{
-
-	0, 1, 1, 3, 1, 2, 1, 2, 3, 2,
-	3, 4, 1, 1, 1, 3, 1, 2, 1, 2,
-	2, 3, 2, 3, 2, 3, 2, 3, 3, 4,
-	3, 3, 2, 2, 1, 5, 3, 1, 3, 2,
-	1, 1, 1, 3, 2, 1, 1, 1, 1, 1,
-	3, 3, 3, 1, 1, 1, 1, 1, 1, 1,
-	3, 1, 1, 1, 1, 1, 1, 1, 1, 1,
-	1, 1, 4, 0, 1, 3, 2, 2, 3, 1,
-	1, 1, 1, 1, 1, 1, 3,
+
+var queryR2 = [...]int8{
+	0, 1, 2, 0, 2, 5, 3, 2, 1, 3,
+	1, 3, 1, 3, 1, 3, 1, 3, 1, 2,
+	1, 2, 2, 3, 1, 1, 1, 2, 3, 3,
+	4, 1, 4, 5, 1, 1, 1, 1, 1, 5,
+	0, 1, 2, 3, 3, 1, 2, 1, 1, 2,
+	2, 1, 5, 3, 1, 3, 2, 1, 1, 1,
+	3, 2, 1, 1, 1, 1, 1, 3, 3, 3,
+	1, 1, 1, 1, 1, 1, 1, 3, 1, 1,
+	1, 1, 1, 1, 1, 1, 1, 1, 1, 1,
+	1, 2, 1, 1, 1, 2, 2, 3, 4, 0,
+	1, 3, 2, 2, 3, 1, 1, 1, 2, 2,
+	1, 1, 1, 1, 1, 3, 3,
 }
-var queryChk = [...]int{
-
-	-1000, -26, -1, -2, -4, -20, -3, 14, 15, 21,
-	-18, 22, 10, 20, 19, 28, 26, 25, 11, -4,
-	-5, 27, 4, -20, -19, 8, 22, 22, 10, 22,
-	22, 22, 22, 22, -2, -5, -11, -12, -13, 27,
-	-14, 28, -15, -16, -6, -7, -8, -9, -10, 14,
-	9, 44, 15, 43, 12, -25, 6, 16, 17, 18,
-	-5, 27, 14, 15, 22, 22, 22, 22, 22, 22,
-	22, 5, 31, 42, -21, 35, 34, -13, -22, 29,
-	30, 32, 33, -14, -23, 40, 41, 36, 37, 38,
-	39, 26, 25, -24, 19, 20, 21, 22, 24, 23,
-	6, 14, 15, 14, -11, -5, 22, -12, -12, -13,
-	-14, -15, 12, 12, -16, -17, -11, 45, 7, 8,
-	7, 11, -12, -11,
+
+var queryChk = [...]int16{
+	-32768, -38, -1, 16, -2, 2, -3, -4, -5, 6,
+	-8, -12, -9, -13, 28, -7, -32, 27, -30, 21,
+	22, 31, 32, 26, 13, -39, 30, 39, -8, -2,
+	-14, 37, -17, -15, 4, -13, 6, -31, 10, -2,
+	2, -40, 17, 18, 2, -3, -4, 7, -14, -16,
+	-23, 2, 53, 30, -24, -25, 37, -26, 38, -27,
+	-28, -18, -19, -20, -21, -22, -6, 21, 11, 8,
+	22, 54, 19, -37, 6, -11, 23, 24, 25, -9,
+	-10, 28, -14, 37, -13, 21, 22, 21, 22, 14,
+	10, 5, 5, 53, 54, 41, 52, -33, 45, 44,
+	-25, 53, 30, -34, 39, 40, 42, 43, -26, -35,
+	50, 51, 46, 47, 48, 49, 36, 35, -36, 29,
+	30, 31, 32, 34, 33, -11, 6, 21, 22, 21,
+	-23, 2, -14, 37, -14, 7, -14, 15, 14, -16,
+	53, 30, -24, -24, -25, 53, -26, -27, -27, -27,
+	-28, -29, -23, 9, 7, 7, -14, 7, -1, 5,
+	53, 10, 7, 13, 14, -24, -23,
 }
-var queryDef = [...]int{
-
-	0, -2, 1, 2, 4, 6, 0, 12, 13, 14,
-	16, 18, 0, 0, 0, 0, 0, 0, 0, 5,
-	7, 0, 0, 9, 17, 0, 19, 20, 0, 22,
-	24, 26, 0, 0, 3, 8, 0, 34, 37, 0,
-	42, 0, 49, 59, 67, 68, 69, 70, 71, 0,
-	0, 0, 79, 80, 81, 82, 0, 83, 84, 85,
-	10, 0, 32, 33, 21, 28, 23, 25, 27, 30,
-	31, 15, 0, 0, 0, 40, 41, 39, 0, 45,
-	46, 47, 48, 44, 0, 0, 0, 53, 54, 55,
-	56, 57, 58, 0, 61, 62, 63, 64, 65, 66,
-	73, 76, 77, 0, 0, 11, 29, 0, 36, 38,
-	43, 50, 51, 52, 60, 0, 74, 78, 86, 0,
-	72, 0, 35, 75,
+
+var queryDef = [...]int8{
+	0, -2, 1, 3, 8, 10, 12, 14, 16, 0,
+	18, 20, 24, 25, 26, 0, 31, 0, 45, 34,
+	35, 36, 47, 48, 0, -2, 0, 0, 19, 0,
+	21, 22, 37, 38, -2, 27, 0, 46, 0, 9,
+	11, 4, 0, 0, 0, 13, 15, 17, 23, 0,
+	0, 0, -2, 0, 51, 54, 0, 59, 0, 66,
+	76, 84, 85, 86, 87, 88, 89, 0, 0, 0,
+	105, 107, 110, 111, 0, 90, 112, 113, 114, 92,
+	93, 94, 28, 29, 0, 49, 50, 0, 0, 7,
+	40, 43, 44, -2, 109, 0, 0, 0, 57, 58,
+	56, 106, 0, 0, 62, 63, 64, 65, 61, 0,
+	0, 0, 70, 71, 72, 73, 74, 75, 0, 78,
+	79, 80, 81, 82, 83, 91, 99, 102, 103, 0,
+	0, 0, 95, 96, 30, 32, 0, 0, 6, 0,
+	41, 0, 0, 53, 55, 108, 60, 67, 68, 69,
+	77, 0, 100, 104, 115, 116, 97, 33, 0, 39,
+	42, 0, 98, 0, 5, 52, 101,
 }
-var queryTok1 = [...]int{
 
+var queryTok1 = [...]int8{
 	1, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
-	3, 3, 3, 27, 3, 3, 3, 24, 29, 3,
-	6, 7, 21, 19, 11, 20, 10, 22, 3, 3,
-	3, 3, 3, 3, 3, 3, 3, 3, 8, 3,
-	26, 3, 25, 31, 9, 3, 3, 3, 3, 3,
+	3, 3, 3, 37, 3, 3, 3, 34, 39, 3,
+	6, 7, 31, 29, 13, 30, 12, 32, 3, 3,
+	3, 3, 3, 3, 3, 3, 3, 3, 10, 14,
+	36, 15, 35, 41, 11, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
-	3, 4, 3, 5, 23, 3, 3, 3, 3, 3,
+	3, 4, 3, 5, 33, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
 	3, 3, 3, 3, 3, 3, 3, 3, 3, 3,
-	3, 3, 3, 44, 30, 45, 28,
+	3, 3, 3, 8, 40, 9, 38,
 }
-var queryTok2 = [...]int{
 
-	2, 3, 12, 13, 14, 15, 16, 17, 18, 32,
-	33, 34, 35, 36, 37, 38, 39, 40, 41, 42,
-	43,
+var queryTok2 = [...]int8{
+	2, 3, 16, 17, 18, 19, 20, 21, 22, 23,
+	24, 25, 26, 27, 28, 42, 43, 44, 45, 46,
+	47, 48, 49, 50, 51, 52, 53, 54, 55,
 }
-var queryTok3 = [...]int{
+
+var queryTok3 = [...]int8{
 	0,
 }
 
@@ -269,7 +342,7 @@ func queryNewParser() queryParser {
 	return &queryParserImpl{}
 }
 
-const queryFlag = -1000
+const queryFlag = -32768
 
 func queryTokname(c int) string {
 	if c >= 1 && c-1 < len(queryToknames) {
@@ -308,9 +381,9 @@ func queryErrorMessage(state, lookAhead int) string {
 	expected := make([]int, 0, 4)
 
 	// Look for shiftable tokens.
-	base := queryPact[state]
+	base := int(queryPact[state])
 	for tok := TOKSTART; tok-1 < len(queryToknames); tok++ {
-		if n := base + tok; n >= 0 && n < queryLast && queryChk[queryAct[n]] == tok {
+		if n := base + tok; n >= 0 && n < queryLast && int(queryChk[int(queryAct[n])]) == tok {
 			if len(expected) == cap(expected) {
 				return res
 			}
@@ -320,13 +393,13 @@ func queryErrorMessage(state, lookAhead int) string {
 
 	if queryDef[state] == -2 {
 		i := 0
-		for queryExca[i] != -1 || queryExca[i+1] != state {
+		for queryExca[i] != -1 || int(queryExca[i+1]) != state {
 			i += 2
 		}
 
 		// Look for tokens that we accept or reduce.
 		for i += 2; queryExca[i] >= 0; i += 2 {
-			tok := queryExca[i]
+			tok := int(queryExca[i])
 			if tok < TOKSTART || queryExca[i+1] == 0 {
 				continue
 			}
@@ -357,30 +430,30 @@ func querylex1(lex queryLexer, lval *querySymType) (char, token int) {
 	token = 0
 	char = lex.Lex(lval)
 	if char <= 0 {
-		token = queryTok1[0]
+		token = int(queryTok1[0])
 		goto out
 	}
 	if char < len(queryTok1) {
-		token = queryTok1[char]
+		token = int(queryTok1[char])
 		goto out
 	}
 	if char >= queryPrivate {
 		if char < queryPrivate+len(queryTok2) {
-			token = queryTok2[char-queryPrivate]
+			token = int(queryTok2[char-queryPrivate])
 			goto out
 		}
 	}
 	for i := 0; i < len(queryTok3); i += 2 {
-		token = queryTok3[i+0]
+		token = int(queryTok3[i+0])
 		if token == char {
-			token = queryTok3[i+1]
+			token = int(queryTok3[i+1])
 			goto out
 		}
 	}
 
 out:
 	if token == 0 {
-		token = queryTok2[1] /* unknown char */
+		token = int(queryTok2[1]) /* unknown char */
 	}
 	if queryDebug >= 3 {
 		__yyfmt__.Printf("lex %s(%d)\n", queryTokname(token), uint(char))
@@ -435,7 +508,7 @@ querystack:
 	queryS[queryp].yys = querystate
 
 querynewstate:
-	queryn = queryPact[querystate]
+	queryn = int(queryPact[querystate])
 	if queryn <= queryFlag {
 		goto querydefault /* simple state */
 	}
@@ -446,8 +519,8 @@ querynewstate:
 	if queryn < 0 || queryn >= queryLast {
 		goto querydefault
 	}
-	queryn = queryAct[queryn]
-	if queryChk[queryn] == querytoken { /* valid shift */
+	queryn = int(queryAct[queryn])
+	if int(queryChk[queryn]) == querytoken { /* valid shift */
 		queryrcvr.char = -1
 		querytoken = -1
 		queryVAL = queryrcvr.lval
@@ -460,7 +533,7 @@ querynewstate:
 
 querydefault:
 	/* default state action */
-	queryn = queryDef[querystate]
+	queryn = int(queryDef[querystate])
 	if queryn == -2 {
 		if queryrcvr.char < 0 {
 			queryrcvr.char, querytoken = querylex1(querylex, &queryrcvr.lval)
@@ -469,18 +542,18 @@ querydefault:
 		/* look through exception table */
 		xi := 0
 		for {
-			if queryExca[xi+0] == -1 && queryExca[xi+1] == querystate {
+			if queryExca[xi+0] == -1 && int(queryExca[xi+1]) == querystate {
 				break
 			}
 			xi += 2
 		}
 		for xi += 2; ; xi += 2 {
-			queryn = queryExca[xi+0]
+			queryn = int(queryExca[xi+0])
 			if queryn < 0 || queryn == querytoken {
 				break
 			}
 		}
-		queryn = queryExca[xi+1]
+		queryn = int(queryExca[xi+1])
 		if queryn < 0 {
 			goto ret0
 		}
@@ -502,10 +575,10 @@ querydefault:
 
 			/* find a state where "error" is a legal shift action */
 			for queryp >= 0 {
-				queryn = queryPact[queryS[queryp].yys] + queryErrCode
+				queryn = int(queryPact[queryS[queryp].yys]) + queryErrCode
 				if queryn >= 0 && queryn < queryLast {
-					querystate = queryAct[queryn] /* simulate a shift of "error" */
-					if queryChk[querystate] == queryErrCode {
+					querystate = int(queryAct[queryn]) /* simulate a shift of "error" */
+					if int(queryChk[querystate]) == queryErrCode {
 						goto querystack
 					}
 				}
@@ -541,7 +614,7 @@ querydefault:
 	querypt := queryp
 	_ = querypt // guard against "declared and not used"
 
-	queryp -= queryR2[queryn]
+	queryp -= int(queryR2[queryn])
 	// queryp is now the index of $0. Perform the default action. Iff the
 	// reduced production is ε, $1 is possibly out of range.
 	if queryp+1 >= len(queryS) {
@@ -552,16 +625,16 @@ querydefault:
 	queryVAL = queryS[queryp+1]
 
 	/* consult goto table to find next state */
-	queryn = queryR1[queryn]
-	queryg := queryPgo[queryn]
+	queryn = int(queryR1[queryn])
+	queryg := int(queryPgo[queryn])
 	queryj := queryg + queryS[queryp].yys + 1
 
 	if queryj >= queryLast {
-		querystate = queryAct[queryg]
+		querystate = int(queryAct[queryg])
 	} else {
-		querystate = queryAct[queryj]
-		if queryChk[querystate] != -queryn {
-			querystate = queryAct[queryg]
+		querystate = int(queryAct[queryj])
+		if int(queryChk[querystate]) != -queryn {
+			querystate = int(queryAct[queryg])
 		}
 	}
 	// dummy call; replaced with literal code
@@ -569,316 +642,421 @@ querydefault:
 
 	case 1:
 		queryDollar = queryS[querypt-1 : querypt+1]
-//line query.y:42
-		{
-			querylex.(*queryLexerImpl).result = queryDollar[1].query
-		}
-	case 2:
-		queryDollar = queryS[querypt-1 : querypt+1]
-//line query.y:49
-		{
-			queryVAL.query = Query{queryDollar[1].path}
-		}
-	case 3:
-		queryDollar = queryS[querypt-3 : querypt+1]
 //line query.y:53
 		{
-			queryVAL.query = append(queryDollar[1].query, queryDollar[3].path)
-		}
-	case 4:
-		queryDollar = queryS[querypt-1 : querypt+1]
-//line query.y:60
-		{
-			queryVAL.path = Path{queryDollar[1].step}
+			querylex.(*queryLexerImpl).result = queryDollar[1].query
 		}
 	case 5:
-		queryDollar = queryS[querypt-2 : querypt+1]
-//line query.y:64
+		queryDollar = queryS[querypt-5 : querypt+1]
+//line query.y:66
 		{
-			queryVAL.path = append(queryDollar[1].path, queryDollar[2].step)
+			l := querylex.(*queryLexerImpl)
+			l.defs = append(l.defs, &Definition{queryDollar[2].str, queryDollar[4].query})
 		}
 	case 6:
-		queryDollar = queryS[querypt-1 : querypt+1]
+		queryDollar = queryS[querypt-3 : querypt+1]
 //line query.y:71
 		{
-			queryVAL.step = &Step{Match: queryDollar[1].str}
-		}
-	case 7:
-		queryDollar = queryS[querypt-2 : querypt+1]
-//line query.y:75
-		{
-			queryVAL.step = &Step{Match: queryDollar[1].str, Filter: queryDollar[2].expr}
+			l := querylex.(*queryLexerImpl)
+			l.imports = append(l.imports, queryDollar[2].str)
 		}
 	case 8:
-		queryDollar = queryS[querypt-3 : querypt+1]
-//line query.y:79
+		queryDollar = queryS[querypt-1 : querypt+1]
+//line query.y:80
 		{
-			queryVAL.step = &Step{Match: queryDollar[1].str, Result: true, Filter: queryDollar[3].expr}
+			queryVAL.query = Query{queryDollar[1].pathExpr}
 		}
 	case 9:
-		queryDollar = queryS[querypt-2 : querypt+1]
-//line query.y:83
+		queryDollar = queryS[querypt-3 : querypt+1]
+//line query.y:84
 		{
-			queryVAL.step = &Step{Axis: queryDollar[1].axis, Match: queryDollar[2].str}
+			queryVAL.query = append(queryDollar[1].query, queryDollar[3].pathExpr)
 		}
 	case 10:
-		queryDollar = queryS[querypt-3 : querypt+1]
-//line query.y:87
+		queryDollar = queryS[querypt-1 : querypt+1]
+//line query.y:88
 		{
-			queryVAL.step = &Step{Axis: queryDollar[1].axis, Match: queryDollar[2].str, Filter: queryDollar[3].expr}
+			queryVAL.query = nil
 		}
-	case 11:
-		queryDollar = queryS[querypt-4 : querypt+1]
-//line query.y:91
+	case 13:
+		queryDollar = queryS[querypt-3 : querypt+1]
+//line query.y:97
 		{
-			queryVAL.step = &Step{Axis: queryDollar[1].axis, Match: queryDollar[2].str, Result: true, Filter: queryDollar[4].expr}
+			queryVAL.pathExpr = &SetExpr{queryDollar[1].pathExpr, queryDollar[2].str, queryDollar[3].pathExpr}
 		}
 	case 15:
 		queryDollar = queryS[querypt-3 : querypt+1]
-//line query.y:104
+//line query.y:105
 		{
-			queryVAL.expr = queryDollar[2].expr
+			queryVAL.pathExpr = &SetExpr{queryDollar[1].pathExpr, queryDollar[2].str, queryDollar[3].pathExpr}
 		}
 	case 16:
 		queryDollar = queryS[querypt-1 : querypt+1]
-//line query.y:111
+//line query.y:112
 		{
-			queryVAL.axis = &Axis{Dir: queryDollar[1].str}
+			queryVAL.pathExpr = queryDollar[1].path
 		}
 	case 17:
-		queryDollar = queryS[querypt-2 : querypt+1]
-//line query.y:115
+		queryDollar = queryS[querypt-3 : querypt+1]
+//line query.y:116
 		{
-			queryVAL.axis = &Axis{queryDollar[1].str, queryDollar[2].str}
+			queryVAL.pathExpr = queryDollar[2].pathExpr
 		}
 	case 18:
 		queryDollar = queryS[querypt-1 : querypt+1]
-//line query.y:121
+//line query.y:123
 		{
-			queryVAL.str = "/"
+			queryVAL.path = Path{queryDollar[1].step}
 		}
 	case 19:
 		queryDollar = queryS[querypt-2 : querypt+1]
-//line query.y:122
-		{
-			queryVAL.str = "//"
-		}
-	case 20:
-		queryDollar = queryS[querypt-2 : querypt+1]
-//line query.y:123
+//line query.y:127
 		{
-			queryVAL.str = "./"
+			queryVAL.path = append(queryDollar[1].path, queryDollar[2].step)
 		}
 	case 21:
-		queryDollar = queryS[querypt-3 : querypt+1]
-//line query.y:124
+		queryDollar = queryS[querypt-2 : querypt+1]
+//line query.y:135
 		{
-			queryVAL.str = ".//"
+			queryDollar[1].step.Filter, queryDollar[1].step.Slice = queryDollar[2].step.Filter, queryDollar[2].step.Slice
+			queryVAL.step = queryDollar[1].step
 		}
 	case 22:
 		queryDollar = queryS[querypt-2 : querypt+1]
-//line query.y:125
+//line query.y:140
 		{
-			queryVAL.str = "-/"
+			queryDollar[1].step.Result = true
+			queryVAL.step = queryDollar[1].step
 		}
 	case 23:
 		queryDollar = queryS[querypt-3 : querypt+1]
-//line query.y:126
+//line query.y:145
+		{
+			queryDollar[1].step.Result = true
+			queryDollar[1].step.Filter, queryDollar[1].step.Slice = queryDollar[3].step.Filter, queryDollar[3].step.Slice
+			queryVAL.step = queryDollar[1].step
+		}
+	case 26:
+		queryDollar = queryS[querypt-1 : querypt+1]
+//line query.y:156
 		{
-			queryVAL.str = "-//"
+			queryVAL.step = &Step{Ref: &Ref{Name: queryDollar[1].str}}
 		}
-	case 24:
+	case 27:
 		queryDollar = queryS[querypt-2 : querypt+1]
-//line query.y:127
+//line query.y:163
 		{
-			queryVAL.str = "+/"
+			queryDollar[2].step.Axis = queryDollar[1].axis
+			queryVAL.step = queryDollar[2].step
 		}
-	case 25:
+	case 28:
 		queryDollar = queryS[querypt-3 : querypt+1]
-//line query.y:128
+//line query.y:168
 		{
-			queryVAL.str = "+//"
+			queryDollar[2].step.Axis = queryDollar[1].axis
+			queryDollar[2].step.Filter, queryDollar[2].step.Slice = queryDollar[3].step.Filter, queryDollar[3].step.Slice
+			queryVAL.step = queryDollar[2].step
 		}
-	case 26:
-		queryDollar = queryS[querypt-2 : querypt+1]
-//line query.y:129
+	case 29:
+		queryDollar = queryS[querypt-3 : querypt+1]
+//line query.y:174
 		{
-			queryVAL.str = "~/"
+			queryDollar[2].step.Axis = queryDollar[1].axis
+			queryDollar[2].step.Result = true
+			queryVAL.step = queryDollar[2].step
 		}
-	case 27:
-		queryDollar = queryS[querypt-3 : querypt+1]
-//line query.y:130
+	case 30:
+		queryDollar = queryS[querypt-4 : querypt+1]
+//line query.y:180
 		{
-			queryVAL.str = "~//"
+			queryDollar[2].step.Axis = queryDollar[1].axis
+			queryDollar[2].step.Result = true
+			queryDollar[2].step.Filter, queryDollar[2].step.Slice = queryDollar[4].step.Filter, queryDollar[4].step.Slice
+			queryVAL.step = queryDollar[2].step
 		}
-	case 28:
-		queryDollar = queryS[querypt-3 : querypt+1]
-//line query.y:131
+	case 31:
+		queryDollar = queryS[querypt-1 : querypt+1]
+//line query.y:190
 		{
-			queryVAL.str = "../"
+			queryVAL.step = &Step{Match: queryDollar[1].str}
 		}
-	case 29:
+	case 32:
 		queryDollar = queryS[querypt-4 : querypt+1]
-//line query.y:132
+//line query.y:194
 		{
-			queryVAL.str = "..//"
+			queryVAL.step = &Step{Not: queryDollar[3].step}
 		}
-	case 30:
+	case 33:
+		queryDollar = queryS[querypt-5 : querypt+1]
+//line query.y:198
+		{
+			queryDollar[3].step.Filter, queryDollar[3].step.Slice = queryDollar[4].step.Filter, queryDollar[4].step.Slice
+			queryVAL.step = &Step{Not: queryDollar[3].step}
+		}
+	case 37:
+		queryDollar = queryS[querypt-1 : querypt+1]
+//line query.y:212
+		{
+			queryVAL.step = newPredicate(queryDollar[1].expr)
+		}
+	case 38:
+		queryDollar = queryS[querypt-1 : querypt+1]
+//line query.y:216
+		{
+			queryVAL.step = &Step{Slice: queryDollar[1].slice}
+		}
+	case 39:
+		queryDollar = queryS[querypt-5 : querypt+1]
+//line query.y:223
+		{
+			queryVAL.slice = &Slice{int(queryDollar[2].num), int(queryDollar[4].num), true}
+		}
+	case 40:
+		queryDollar = queryS[querypt-0 : querypt+1]
+//line query.y:230
+		{
+			queryVAL.num = 0
+		}
+	case 42:
+		queryDollar = queryS[querypt-2 : querypt+1]
+//line query.y:235
+		{
+			queryVAL.num = -queryDollar[2].num
+		}
+	case 43:
 		queryDollar = queryS[querypt-3 : querypt+1]
-//line query.y:133
+//line query.y:242
 		{
-			queryVAL.str = "<//"
+			queryVAL.expr = queryDollar[2].expr
 		}
-	case 31:
+	case 44:
 		queryDollar = queryS[querypt-3 : querypt+1]
-//line query.y:134
+//line query.y:246
 		{
-			queryVAL.str = ">//"
+			queryVAL.expr = nil
 		}
-	case 32:
+	case 45:
+		queryDollar = queryS[querypt-1 : querypt+1]
+//line query.y:253
+		{
+			queryVAL.axis = &Axis{Dir: queryDollar[1].str}
+		}
+	case 46:
 		queryDollar = queryS[querypt-2 : querypt+1]
-//line query.y:138
+//line query.y:257
+		{
+			queryVAL.axis = &Axis{queryDollar[1].str, queryDollar[2].str}
+		}
+	case 49:
+		queryDollar = queryS[querypt-2 : querypt+1]
+//line query.y:268
 		{
 			queryVAL.str = queryDollar[2].str
 		}
-	case 33:
+	case 50:
 		queryDollar = queryS[querypt-2 : querypt+1]
-//line query.y:139
+//line query.y:269
 		{
 			queryVAL.str = queryDollar[2].str
 		}
-	case 35:
+	case 52:
 		queryDollar = queryS[querypt-5 : querypt+1]
-//line query.y:145
+//line query.y:275
 		{
 			queryVAL.expr = &Cond{queryDollar[1].expr, queryDollar[3].expr, queryDollar[5].expr}
 		}
-	case 36:
+	case 53:
 		queryDollar = queryS[querypt-3 : querypt+1]
-//line query.y:149
+//line query.y:279
 		{
 			queryVAL.expr = &Cond{queryDollar[1].expr, nil, queryDollar[3].expr}
 		}
-	case 38:
+	case 55:
 		queryDollar = queryS[querypt-3 : querypt+1]
-//line query.y:157
+//line query.y:287
 		{
 			queryVAL.expr = &Binary{queryDollar[1].expr, queryDollar[2].str, queryDollar[3].expr}
 		}
-	case 39:
+	case 56:
 		queryDollar = queryS[querypt-2 : querypt+1]
-//line query.y:161
+//line query.y:291
 		{
 			queryVAL.expr = &Unary{queryDollar[1].str, queryDollar[2].expr}
 		}
-	case 43:
+	case 60:
 		queryDollar = queryS[querypt-3 : querypt+1]
-//line query.y:174
+//line query.y:304
 		{
 			queryVAL.expr = &Binary{queryDollar[1].expr, queryDollar[2].str, queryDollar[3].expr}
 		}
-	case 44:
+	case 61:
 		queryDollar = queryS[querypt-2 : querypt+1]
-//line query.y:178
+//line query.y:308
 		{
 			queryVAL.expr = &Unary{queryDollar[1].str, queryDollar[2].expr}
 		}
-	case 50:
+	case 67:
 		queryDollar = queryS[querypt-3 : querypt+1]
-//line query.y:193
+//line query.y:323
 		{
 			queryVAL.expr = &Binary{queryDollar[1].expr, queryDollar[2].str, queryDollar[3].expr}
 		}
-	case 51:
+	case 68:
 		queryDollar = queryS[querypt-3 : querypt+1]
-//line query.y:197
+//line query.y:327
 		{
-			queryVAL.expr = &Binary{queryDollar[1].expr, queryDollar[2].str, queryDollar[3].regexp}
+			queryVAL.expr = &Binary{queryDollar[1].expr, queryDollar[2].str, queryDollar[3].expr}
 		}
-	case 52:
+	case 69:
 		queryDollar = queryS[querypt-3 : querypt+1]
-//line query.y:201
+//line query.y:331
 		{
-			queryVAL.expr = &Binary{queryDollar[1].expr, queryDollar[2].str, queryDollar[3].regexp}
+			queryVAL.expr = &Binary{queryDollar[1].expr, queryDollar[2].str, queryDollar[3].expr}
 		}
-	case 60:
+	case 77:
 		queryDollar = queryS[querypt-3 : querypt+1]
-//line query.y:218
+//line query.y:348
 		{
 			queryVAL.expr = &Binary{queryDollar[1].expr, queryDollar[2].str, queryDollar[3].expr}
 		}
-	case 72:
+	case 89:
+		queryDollar = queryS[querypt-1 : querypt+1]
+//line query.y:369
+		{
+			queryVAL.expr = queryDollar[1].path
+		}
+	case 90:
+		queryDollar = queryS[querypt-1 : querypt+1]
+//line query.y:376
+		{
+			queryVAL.path = Path{queryDollar[1].step}
+		}
+	case 91:
+		queryDollar = queryS[querypt-2 : querypt+1]
+//line query.y:380
+		{
+			queryVAL.path = append(queryDollar[1].path, queryDollar[2].step)
+		}
+	case 94:
+		queryDollar = queryS[querypt-1 : querypt+1]
+//line query.y:392
+		{
+			queryVAL.step = &Step{Ref: &Ref{Name: queryDollar[1].str}}
+		}
+	case 95:
+		queryDollar = queryS[querypt-2 : querypt+1]
+//line query.y:396
+		{
+			queryVAL.step = &Step{Ref: &Ref{Name: queryDollar[1].str}, Filter: queryDollar[2].step.Filter, Slice: queryDollar[2].step.Slice}
+		}
+	case 96:
+		queryDollar = queryS[querypt-2 : querypt+1]
+//line query.y:400
+		{
+			queryVAL.step = &Step{Ref: &Ref{Name: queryDollar[1].str}, Result: true}
+		}
+	case 97:
+		queryDollar = queryS[querypt-3 : querypt+1]
+//line query.y:404
+		{
+			queryVAL.step = &Step{Ref: &Ref{Name: queryDollar[1].str}, Result: true, Filter: queryDollar[3].step.Filter, Slice: queryDollar[3].step.Slice}
+		}
+	case 98:
 		queryDollar = queryS[querypt-4 : querypt+1]
-//line query.y:242
+//line query.y:411
 		{
 			queryVAL.expr = &FuncCall{queryDollar[1].str, queryDollar[3].args}
 		}
-	case 73:
+	case 99:
 		queryDollar = queryS[querypt-0 : querypt+1]
-//line query.y:249
+//line query.y:418
 		{
 			queryVAL.args = nil
 		}
-	case 74:
+	case 100:
 		queryDollar = queryS[querypt-1 : querypt+1]
-//line query.y:253
+//line query.y:422
 		{
 			queryVAL.args = []Expr{queryDollar[1].expr}
 		}
-	case 75:
+	case 101:
 		queryDollar = queryS[querypt-3 : querypt+1]
-//line query.y:257
+//line query.y:426
 		{
 			queryVAL.args = append(queryDollar[1].args, queryDollar[3].expr)
 		}
-	case 76:
+	case 102:
 		queryDollar = queryS[querypt-2 : querypt+1]
-//line query.y:263
+//line query.y:432
 		{
 			queryVAL.expr = &WithAttr{queryDollar[2].str}
 		}
-	case 77:
+	case 103:
 		queryDollar = queryS[querypt-2 : querypt+1]
-//line query.y:264
+//line query.y:433
 		{
 			queryVAL.expr = &WithAttr{queryDollar[2].str}
 		}
-	case 78:
+	case 104:
 		queryDollar = queryS[querypt-3 : querypt+1]
-//line query.y:269
+//line query.y:438
 		{
 			queryVAL.expr = QueryParam(queryDollar[2].str)
 		}
-	case 79:
+	case 105:
 		queryDollar = queryS[querypt-1 : querypt+1]
-//line query.y:275
+//line query.y:444
 		{
 			queryVAL.expr = Str(queryDollar[1].str)
 		}
-	case 80:
+	case 106:
 		queryDollar = queryS[querypt-1 : querypt+1]
-//line query.y:276
+//line query.y:445
 		{
 			queryVAL.expr = Num(queryDollar[1].num)
 		}
-	case 81:
+	case 107:
+		queryDollar = queryS[querypt-1 : querypt+1]
+//line query.y:446
+		{
+			queryVAL.expr = Float(queryDollar[1].float)
+		}
+	case 108:
+		queryDollar = queryS[querypt-2 : querypt+1]
+//line query.y:447
+		{
+			queryVAL.expr = Num(-queryDollar[2].num)
+		}
+	case 109:
+		queryDollar = queryS[querypt-2 : querypt+1]
+//line query.y:448
+		{
+			queryVAL.expr = Float(-queryDollar[2].float)
+		}
+	case 110:
 		queryDollar = queryS[querypt-1 : querypt+1]
-//line query.y:277
+//line query.y:449
 		{
 			queryVAL.expr = queryDollar[1].regexp
 		}
-	case 82:
+	case 111:
 		queryDollar = queryS[querypt-1 : querypt+1]
-//line query.y:278
+//line query.y:450
 		{
 			queryVAL.expr = Keyword(queryDollar[1].str)
 		}
-	case 86:
+	case 115:
 		queryDollar = queryS[querypt-3 : querypt+1]
-//line query.y:289
+//line query.y:461
 		{
 			queryVAL.expr = queryDollar[2].expr
 		}
+	case 116:
+		queryDollar = queryS[querypt-3 : querypt+1]
+//line query.y:465
+		{
+			queryVAL.expr = nil
+		}
 	}
 	goto querystack /* stack new state and value */
 }
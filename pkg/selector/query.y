@@ -4,34 +4,45 @@ package selector
 
 %union {
     query Query
+    pathExpr PathExpr
     path Path
     axis *Axis
     step *Step
+    slice *Slice
     expr Expr
     args []Expr
     regexp *Regexp
     err error
     str string
     num int64
+    float float64
 }
 
 %type <query>   query
-%type <path>    path
+%type <pathExpr> set_expr set_term set_factor
+%type <path>    path subquery
 %type <axis>    axis
-%type <step>    step
+%type <step>    step axis_step ref_step subquery_step step_test node_test predicate
+%type <slice>   slice
+%type <num>     position
 %type <expr>    filter func_call attr_ref query_param literal parenthesis
 %type <expr>    expr expr1 expr2 expr3 expr4 expr5
 %type <args>    func_args
 %type <str>     axis_direction axis_type match logical_op bitwise_op relational_op arithmethical_op value
 
-%token '[' ']' '(' ')' ':' '@' '.' ','
+%token '[' ']' '(' ')' '{' '}' ':' '@' '.' ',' ';' '='
+%token LIBRARY LET IMPORT
 
 %token <regexp> REGEXP
 %token <err>    ERR
-%token <str>    ID STR TRUE FALSE NULL
+%token <str>    ID STR TRUE FALSE NULL AXIS NOT REF
 %token <str>    '+' '-' '*' '/' '^' '%' '>' '<' '!' '~' '&' '|' '?'
 %token <str>    LSHIFT RSHIFT AND OR EQ NE LTE GTE MATCH NONMATCH ELSE_OR
 %token <num>    NUM
+%token <float>  FLOAT
+
+%nonassoc SUBQUERY
+%nonassoc '/'
 
 %start top
 
@@ -42,17 +53,69 @@ top:
     {
         querylex.(*queryLexerImpl).result = $1
     }
+|   LIBRARY defs
+    ;
+
+defs:
+    /* empty */
+|   defs def
+    ;
+
+def:
+    LET ID '=' query ';'
+    {
+        l := querylex.(*queryLexerImpl)
+        l.defs = append(l.defs, &Definition { $2, $4 })
+    }
+|   IMPORT STR ';'
+    {
+        l := querylex.(*queryLexerImpl)
+        l.imports = append(l.imports, $2)
+    }
+|   error ';'
     ;
 
 query:
-    path
+    set_expr
     {
         $$ = Query { $1 }
     }
-|   query ',' path
+|   query ',' set_expr
     {
         $$ = append($1, $3)
     }
+|   error
+    {
+        $$ = nil
+    }
+|   query ',' error
+    ;
+
+set_expr:
+    set_term
+|   set_expr '-' set_term
+    {
+        $$ = &SetExpr { $1, $2, $3 }
+    }
+    ;
+
+set_term:
+    set_factor
+|   set_term '&' set_factor
+    {
+        $$ = &SetExpr { $1, $2, $3 }
+    }
+    ;
+
+set_factor:
+    path
+    {
+        $$ = $1
+    }
+|   '(' set_expr ')'
+    {
+        $$ = $2
+    }
     ;
 
 path:
@@ -67,29 +130,74 @@ path:
     ;
 
 step:
-    match
+    step_test
+|   step_test predicate
     {
-        $$ = &Step { Match: $1 }
+        $1.Filter, $1.Slice = $2.Filter, $2.Slice
+        $$ = $1
     }
-|   match filter
+|   step_test '!'
     {
-        $$ = &Step { Match: $1, Filter: $2 }
+        $1.Result = true
+        $$ = $1
     }
-|   match '!' filter
+|   step_test '!' predicate
     {
-        $$ = &Step { Match: $1, Result: true, Filter: $3 }
+        $1.Result = true
+        $1.Filter, $1.Slice = $3.Filter, $3.Slice
+        $$ = $1
     }
-|   axis match
+|   axis_step
+    ;
+
+step_test:
+    node_test
+|   REF
     {
-        $$ = &Step { Axis: $1, Match: $2 }
+        $$ = &Step { Ref: &Ref { Name: $1 } }
     }
-|   axis match filter
+    ;
+
+axis_step:
+    axis node_test
     {
-        $$ = &Step { Axis: $1, Match: $2, Filter: $3 }
+        $2.Axis = $1
+        $$ = $2
     }
-|   axis match '!' filter
+|   axis node_test predicate
     {
-        $$ = &Step { Axis: $1, Match: $2, Result: true, Filter: $4 }
+        $2.Axis = $1
+        $2.Filter, $2.Slice = $3.Filter, $3.Slice
+        $$ = $2
+    }
+|   axis node_test '!'
+    {
+        $2.Axis = $1
+        $2.Result = true
+        $$ = $2
+    }
+|   axis node_test '!' predicate
+    {
+        $2.Axis = $1
+        $2.Result = true
+        $2.Filter, $2.Slice = $4.Filter, $4.Slice
+        $$ = $2
+    }
+    ;
+
+node_test:
+    match
+    {
+        $$ = &Step { Match: $1 }
+    }
+|   NOT '(' node_test ')'
+    {
+        $$ = &Step { Not: $3 }
+    }
+|   NOT '(' node_test predicate ')'
+    {
+        $3.Filter, $3.Slice = $4.Filter, $4.Slice
+        $$ = &Step { Not: $3 }
     }
     ;
 
@@ -99,11 +207,45 @@ match:
 |   '*'
     ;
 
+predicate:
+    filter
+    {
+        $$ = newPredicate($1)
+    }
+|   slice
+    {
+        $$ = &Step { Slice: $1 }
+    }
+    ;
+
+slice:
+    '[' position ':' position ']'
+    {
+        $$ = &Slice { int($2), int($4), true }
+    }
+    ;
+
+position:
+    /* empty */
+    {
+        $$ = 0
+    }
+|   NUM
+|   '-' NUM
+    {
+        $$ = -$2
+    }
+    ;
+
 filter:
     '[' expr ']'
     {
         $$ = $2
     }
+|   '[' error ']'
+    {
+        $$ = nil
+    }
     ;
 
 axis:
@@ -118,20 +260,8 @@ axis:
     ;
 
 axis_direction:
-    '/'             { $$ = "/" }
-|   '/' '/'         { $$ = "//" }
-|   '.' '/'         { $$ = "./" }
-|   '.' '/' '/'     { $$ = ".//" }
-|   '-' '/'         { $$ = "-/" }
-|   '-' '/' '/'     { $$ = "-//" }
-|   '+' '/'         { $$ = "+/" }
-|   '+' '/' '/'     { $$ = "+//" }
-|   '~' '/'         { $$ = "~/" }
-|   '~' '/' '/'     { $$ = "~//" }
-|   '.' '.' '/'     { $$ = "../" }
-|   '.' '.' '/' '/' { $$ = "..//" }
-|   '<' '/' '/'     { $$ = "<//" }
-|   '>' '/' '/'     { $$ = ">//" }
+    '/'
+|   AXIS
     ;
 
 axis_type:
@@ -193,11 +323,11 @@ expr3:
     {
         $$ = &Binary{ $1, $2, $3 }
     }
-|   expr4 MATCH REGEXP
+|   expr4 MATCH expr4
     {
         $$ = &Binary{ $1, $2, $3 }
     }
-|   expr4 NONMATCH REGEXP
+|   expr4 NONMATCH expr4
     {
         $$ = &Binary{ $1, $2, $3 }
     }
@@ -235,6 +365,45 @@ expr5:
 |   query_param
 |   literal
 |   parenthesis
+|   subquery %prec SUBQUERY
+    {
+        $$ = $1
+    }
+    ;
+
+subquery:
+    subquery_step
+    {
+        $$ = Path { $1 }
+    }
+|   subquery subquery_step
+    {
+        $$ = append($1, $2)
+    }
+    ;
+
+subquery_step:
+    axis_step
+|   ref_step
+    ;
+
+ref_step:
+    REF
+    {
+        $$ = &Step { Ref: &Ref { Name: $1 } }
+    }
+|   REF predicate
+    {
+        $$ = &Step { Ref: &Ref { Name: $1 }, Filter: $2.Filter, Slice: $2.Slice }
+    }
+|   REF '!'
+    {
+        $$ = &Step { Ref: &Ref { Name: $1 }, Result: true }
+    }
+|   REF '!' predicate
+    {
+        $$ = &Step { Ref: &Ref { Name: $1 }, Result: true, Filter: $3.Filter, Slice: $3.Slice }
+    }
     ;
 
 func_call:
@@ -274,6 +443,9 @@ query_param:
 literal:
     STR     { $$ = Str($1) }
 |   NUM     { $$ = Num($1) }
+|   FLOAT   { $$ = Float($1) }
+|   '-' NUM     { $$ = Num(-$2) }
+|   '-' FLOAT   { $$ = Float(-$2) }
 |   REGEXP  { $$ = $1 }
 |   value   { $$ = Keyword($1)}
     ;
@@ -289,6 +461,10 @@ parenthesis:
     {
         $$ = $2
     }
+|   '(' error ')'
+    {
+        $$ = nil
+    }
     ;
 
 %%
@@ -0,0 +1,148 @@
+package selector
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const setSource = `
+package test
+
+type T struct{}
+
+func (t *T) Close() {}
+
+func Open() {
+	defer println("done")
+}
+
+func Read() int {
+	return 0
+}
+
+func Write() {
+	func() {
+		defer println("done")
+	}()
+}
+`
+
+func TestSetExpr(t *testing.T) {
+	Convey("Given a parser", t, func() {
+		Convey("When parse query with set operators", func() {
+			var queries = map[string]PathExpr{
+				"//A & //B": &SetExpr{
+					Path{&Step{Axis: &Axis{Dir: "//"}, Match: "A"}},
+					"&",
+					Path{&Step{Axis: &Axis{Dir: "//"}, Match: "B"}},
+				},
+				"//A - //B & //C": &SetExpr{
+					Path{&Step{Axis: &Axis{Dir: "//"}, Match: "A"}},
+					"-",
+					&SetExpr{
+						Path{&Step{Axis: &Axis{Dir: "//"}, Match: "B"}},
+						"&",
+						Path{&Step{Axis: &Axis{Dir: "//"}, Match: "C"}},
+					},
+				},
+				"(//A - //B) & //C": &SetExpr{
+					&SetExpr{
+						Path{&Step{Axis: &Axis{Dir: "//"}, Match: "A"}},
+						"-",
+						Path{&Step{Axis: &Axis{Dir: "//"}, Match: "B"}},
+					},
+					"&",
+					Path{&Step{Axis: &Axis{Dir: "//"}, Match: "C"}},
+				},
+			}
+
+			for q, expected := range queries {
+				parsed, err := ParseQuery(q)
+
+				So(err, ShouldBeNil)
+				So(parsed, ShouldResemble, Query{expected})
+				So(parsed.String(), ShouldEqual, q)
+			}
+
+			parsed, err := ParseQuery("//A - //B - //C")
+
+			So(err, ShouldBeNil)
+			So(parsed[0].(*SetExpr).Lhs, ShouldHaveSameTypeAs, &SetExpr{})
+			So(parsed.String(), ShouldEqual, "//A - //B - //C")
+
+			parsed, err = ParseQuery("//A - (//B - //C)")
+
+			So(err, ShouldBeNil)
+			So(parsed.String(), ShouldEqual, "//A - (//B - //C)")
+		})
+
+		Convey("When parse query with negated step", func() {
+			var steps = map[string]*Step{
+				"//not(FuncDecl)":                  {Axis: &Axis{Dir: "//"}, Not: &Step{Match: "FuncDecl"}},
+				"//not(FuncDecl [@method]) !":      {Axis: &Axis{Dir: "//"}, Not: &Step{Match: "FuncDecl", Filter: &WithAttr{"method"}}, Result: true},
+				"/:Body not(not(*)) [depth() > 1]": {Axis: &Axis{Dir: "/", Type: "Body"}, Not: &Step{Not: &Step{Match: "*"}}, Filter: &Binary{&FuncCall{"depth", nil}, ">", Num(1)}},
+			}
+
+			for q, expected := range steps {
+				parsed, err := ParseQuery(q)
+
+				So(err, ShouldBeNil)
+				So(parsed, ShouldResemble, Query{Path{expected}})
+				So(parsed.String(), ShouldEqual, q)
+			}
+
+			parsed, err := ParseQuery("// not [@name]")
+
+			So(err, ShouldBeNil)
+			So(parsed, ShouldResemble, Query{Path{
+				&Step{Axis: &Axis{Dir: "//"}, Match: "not", Filter: &WithAttr{"name"}},
+			}})
+		})
+	})
+
+	Convey("Given a parsed file", t, func() {
+		_, f := parseSource(setSource)
+
+		Convey("When evaluate query with set operators", func() {
+			var queries = map[string][]string{
+				"// FuncDecl & // FuncDecl [ @exported ]":                                      {"Close", "Open", "Read", "Write"},
+				"// FuncDecl - // FuncDecl [ @method ]":                                        {"Open", "Read", "Write"},
+				"// FuncDecl - // FuncDecl [ @method ] - // DeferStmt ..// FuncDecl":           {"Read"},
+				"// FuncDecl - (// FuncDecl [ @method ] - // FuncDecl [ @name == \"Close\" ])": {"Close", "Open", "Read", "Write"},
+				"// FuncDecl & // DeferStmt ..// FuncDecl, // FuncDecl [ @method ]":            {"Close", "Open", "Write"},
+			}
+
+			for q, expected := range queries {
+				nodes, err := evalQuery(f, q)
+
+				So(err, ShouldBeNil)
+				So(funcNames(nodes), ShouldResemble, expected)
+			}
+		})
+
+		Convey("When evaluate query with negated step", func() {
+			nodes, err := evalQuery(f, "/ not(FuncDecl)")
+
+			So(err, ShouldBeNil)
+			So(typeNames(nodes), ShouldResemble, []string{"Ident", "GenDecl"})
+
+			nodes, err = evalQuery(f, "/ not(FuncDecl [ @method ]) [ type() == \"FuncDecl\" ]")
+
+			So(err, ShouldBeNil)
+			So(funcNames(nodes), ShouldResemble, []string{"Open", "Read", "Write"})
+		})
+
+		Convey("When evaluate set operators on nodesets", func() {
+			nodes, err := evalQuery(f, "/ FuncDecl [ count(.// DeferStmt - /:Body BlockStmt /:List DeferStmt) > 0 ]")
+
+			So(err, ShouldBeNil)
+			So(funcNames(nodes), ShouldResemble, []string{"Write"})
+
+			nodes, err = evalQuery(f, "/ FuncDecl [ .// DeferStmt & /:Body BlockStmt /:List DeferStmt ]")
+
+			So(err, ShouldBeNil)
+			So(funcNames(nodes), ShouldResemble, []string{"Open"})
+		})
+	})
+}
@@ -0,0 +1,111 @@
+package selector
+
+import (
+	"go/ast"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const sliceSource = `
+package test
+
+func Foo(n int) int {
+	a := 1
+	b := 2
+	if n > 0 {
+		return a
+	}
+	return b
+}
+
+func Bar() {
+	println()
+}
+`
+
+func TestSlice(t *testing.T) {
+	Convey("Given a parser", t, func() {
+		Convey("When parse query with positional predicates", func() {
+			var slices = map[string]*Slice{
+				"* [1]":    {1, 1, false},
+				"* [-1]":   {-1, -1, false},
+				"* [2:5]":  {2, 5, true},
+				"* [2:]":   {2, 0, true},
+				"* [:-2]":  {0, -2, true},
+				"* [:]":    {0, 0, true},
+				"* ![-2:]": {-2, 0, true},
+			}
+
+			for q, expected := range slices {
+				parsed, err := ParseQuery(q)
+
+				So(err, ShouldBeNil)
+				So(parsed[0].(Path)[0].Slice, ShouldResemble, expected)
+				So(parsed[0].(Path)[0].Filter, ShouldBeNil)
+				So(parsed.String(), ShouldEqual, q)
+			}
+		})
+	})
+
+	Convey("Given some nodes", t, func() {
+		nodes := []ast.Node{ast.NewIdent("a"), ast.NewIdent("b"), ast.NewIdent("c"), ast.NewIdent("d")}
+
+		names := func(nodes []ast.Node) (names []string) {
+			for _, node := range nodes {
+				names = append(names, node.(*ast.Ident).Name)
+			}
+
+			return
+		}
+
+		Convey("When select nodes by position", func() {
+			var slices = map[Slice][]string{
+				{1, 1, false}:   {"a"},
+				{-1, -1, false}: {"d"},
+				{5, 5, false}:   nil,
+				{0, 0, false}:   nil,
+				{2, 3, true}:    {"b", "c"},
+				{2, 0, true}:    {"b", "c", "d"},
+				{0, -2, true}:   {"a", "b", "c"},
+				{-3, 9, true}:   {"b", "c", "d"},
+				{3, 2, true}:    nil,
+			}
+
+			for slice, expected := range slices {
+				So(names(slice.Select(nodes)), ShouldResemble, expected)
+			}
+		})
+	})
+
+	Convey("Given a parsed file", t, func() {
+		_, f := parseSource(sliceSource)
+
+		Convey("When evaluate the first statement of each function body", func() {
+			nodes, err := evalQuery(f, "// FuncDecl /:Body BlockStmt /:List * [1]")
+
+			So(err, ShouldBeNil)
+			So(typeNames(nodes), ShouldResemble, []string{"AssignStmt", "ExprStmt"})
+		})
+
+		Convey("When evaluate the last return of a function", func() {
+			nodes, err := evalQuery(f, "// FuncDecl // ReturnStmt [-1] / Ident")
+
+			So(err, ShouldBeNil)
+			So(nodes, ShouldHaveLength, 1)
+			So(nodes[0].(*ast.Ident).Name, ShouldEqual, "b")
+		})
+
+		Convey("When evaluate a range of statements", func() {
+			nodes, err := evalQuery(f, "// FuncDecl /:Body BlockStmt /:List * [2:3]")
+
+			So(err, ShouldBeNil)
+			So(typeNames(nodes), ShouldResemble, []string{"AssignStmt", "IfStmt"})
+
+			nodes, err = evalQuery(f, "// FuncDecl /:Body BlockStmt /:List * [-2:]")
+
+			So(err, ShouldBeNil)
+			So(typeNames(nodes), ShouldResemble, []string{"IfStmt", "ReturnStmt", "ExprStmt"})
+		})
+	})
+}
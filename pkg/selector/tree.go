@@ -0,0 +1,439 @@
+package selector
+
+import (
+	"go/ast"
+	"go/token"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+type treeNode struct {
+	parent ast.Node
+	order  int
+	last   int
+	depth  int
+}
+
+// tree indexes a syntax tree in document order so the axes can navigate it.
+//
+// The tree only records the nodes in document order with their parents when built,
+// the children, the field names and the nodes of type names are collected on the first lookup,
+// since most of queries only use a few of them.
+type tree struct {
+	root    ast.Node
+	adapter Tree // the adapter of non-go/ast tree, or nil for go/ast tree
+	nodes   []ast.Node
+	info    map[ast.Node]*treeNode
+
+	mu         sync.Mutex // guards the lazily collected lookups
+	childLists map[ast.Node][]ast.Node
+	fields     map[ast.Node]string // the parent field names of the nodes
+	named      map[ast.Node]bool   // the parent nodes whose children are named
+	types      map[string][]ast.Node
+}
+
+func newTree(root ast.Node) *tree {
+	// count the nodes first, so the nodes are allocated at once
+	size := 0
+
+	ast.Inspect(root, func(n ast.Node) bool {
+		if n != nil {
+			size++
+		}
+
+		return true
+	})
+
+	t := &tree{root: root, nodes: make([]ast.Node, 0, size), info: make(map[ast.Node]*treeNode, size)}
+
+	var stack []ast.Node
+	infos := make([]treeNode, size)
+
+	ast.Inspect(root, func(n ast.Node) bool {
+		if n == nil {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+
+			t.info[top].last = len(t.nodes) - 1
+
+			return false
+		}
+
+		if _, exists := t.info[n]; exists {
+			return false
+		}
+
+		info := &infos[0]
+		infos = infos[1:]
+
+		info.order, info.depth = len(t.nodes), len(stack)+1
+
+		if len(stack) > 0 {
+			info.parent = stack[len(stack)-1]
+		}
+
+		t.nodes = append(t.nodes, n)
+		t.info[n] = info
+
+		stack = append(stack, n)
+
+		return true
+	})
+
+	return t
+}
+
+func (t *tree) contains(n ast.Node) bool {
+	_, ok := t.info[n]
+
+	return ok
+}
+
+func (t *tree) parent(n ast.Node) ast.Node {
+	if info, ok := t.info[n]; ok {
+		return info.parent
+	}
+
+	return nil
+}
+
+// field returns the name of parent field which holds the node,
+// the fields of all the children are named on the first lookup, since the names are only used by the axis types.
+func (t *tree) field(n ast.Node) string {
+	parent := t.parent(n)
+
+	if parent == nil {
+		return ""
+	}
+
+	children := t.children(parent)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.named[parent] {
+		if t.named == nil {
+			t.named = make(map[ast.Node]bool)
+			t.fields = make(map[ast.Node]string)
+		}
+
+		t.named[parent] = true
+		t.nameFields(parent, children)
+	}
+
+	return t.fields[n]
+}
+
+func (t *tree) nameFields(parent ast.Node, children []ast.Node) {
+	if t.adapter != nil {
+		if fields, ok := t.adapter.(FieldTree); ok {
+			for _, child := range children {
+				t.fields[child] = fields.Field(parent, child)
+			}
+		}
+
+		return
+	}
+
+	names := fieldsOf(parent)
+
+	for _, child := range children {
+		t.fields[child] = names[child]
+	}
+}
+
+// reachedBy reports whether the node is held by the parent field with the name.
+func (t *tree) reachedBy(n ast.Node, field string) bool {
+	if t.field(n) == field {
+		return true
+	}
+
+	if parent, ok := t.parent(n).(*ast.FieldList); ok {
+		return t.field(parent) == field
+	}
+
+	return false
+}
+
+// children returns the child nodes, which are the nodes skipping the descendants of the previous child.
+func (t *tree) children(n ast.Node) []ast.Node {
+	info, ok := t.info[n]
+
+	if !ok {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	children, ok := t.childLists[n]
+
+	if !ok {
+		for i := info.order + 1; i <= info.last; i = t.info[t.nodes[i]].last + 1 {
+			children = append(children, t.nodes[i])
+		}
+
+		if t.childLists == nil {
+			t.childLists = make(map[ast.Node][]ast.Node)
+		}
+
+		t.childLists[n] = children
+	}
+
+	return children
+}
+
+func (t *tree) descendants(n ast.Node) []ast.Node {
+	if info, ok := t.info[n]; ok {
+		return t.nodes[info.order+1 : info.last+1]
+	}
+
+	return nil
+}
+
+// typed returns the nodes of the type name in document order,
+// the nodes of each type name are collected on the first lookup of the type name.
+func (t *tree) typed(name string) []ast.Node {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	nodes, ok := t.types[name]
+
+	if !ok {
+		for _, n := range t.nodes {
+			if t.typeOf(n) == name {
+				nodes = append(nodes, n)
+			}
+		}
+
+		if t.types == nil {
+			t.types = make(map[string][]ast.Node)
+		}
+
+		t.types[name] = nodes
+	}
+
+	return nodes
+}
+
+// typedDescendants returns the descendant nodes of the type name, with the node itself if self is true.
+func (t *tree) typedDescendants(n ast.Node, name string, self bool) []ast.Node {
+	info, ok := t.info[n]
+
+	if !ok {
+		return nil
+	}
+
+	nodes := t.typed(name)
+
+	first := info.order + 1
+
+	if self {
+		first = info.order
+	}
+
+	i := sort.Search(len(nodes), func(i int) bool { return t.info[nodes[i]].order >= first })
+	j := sort.Search(len(nodes), func(i int) bool { return t.info[nodes[i]].order > info.last })
+
+	return nodes[i:j]
+}
+
+func (t *tree) siblings(n ast.Node) (left, right []ast.Node) {
+	parent := t.parent(n)
+
+	if parent == nil {
+		return
+	}
+
+	children := t.children(parent)
+
+	for i, child := range children {
+		if child == n {
+			return children[:i], children[i+1:]
+		}
+	}
+
+	return
+}
+
+func (t *tree) ancestors(n ast.Node) (nodes []ast.Node) {
+	for parent := t.parent(n); parent != nil; parent = t.parent(parent) {
+		nodes = append(nodes, parent)
+	}
+
+	return
+}
+
+func (t *tree) preceding(n ast.Node) (nodes []ast.Node) {
+	info, ok := t.info[n]
+
+	if !ok {
+		return
+	}
+
+	ancestors := make(map[ast.Node]bool)
+
+	for _, parent := range t.ancestors(n) {
+		ancestors[parent] = true
+	}
+
+	for _, node := range t.nodes[:info.order] {
+		if !ancestors[node] {
+			nodes = append(nodes, node)
+		}
+	}
+
+	return
+}
+
+func (t *tree) following(n ast.Node) []ast.Node {
+	if info, ok := t.info[n]; ok {
+		return t.nodes[info.last+1:]
+	}
+
+	return nil
+}
+
+func (t *tree) depth(n ast.Node) int {
+	if info, ok := t.info[n]; ok {
+		return info.depth
+	}
+
+	return 0
+}
+
+func (t *tree) order(n ast.Node) int {
+	if info, ok := t.info[n]; ok {
+		return info.order
+	}
+
+	return -1
+}
+
+// sort removes the duplicated nodes and sorts the rest in document order.
+func (t *tree) sort(nodes []ast.Node) []ast.Node {
+	seen := make(map[ast.Node]bool, len(nodes))
+	sorted := make([]ast.Node, 0, len(nodes))
+
+	for _, n := range nodes {
+		if !seen[n] {
+			seen[n] = true
+			sorted = append(sorted, n)
+		}
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return t.order(sorted[i]) < t.order(sorted[j])
+	})
+
+	return sorted
+}
+
+// fieldOf returns the name of the parent struct field which holds the child node.
+func fieldOf(parent, child ast.Node) string {
+	return fieldsOf(parent)[child]
+}
+
+// fieldsOf returns the names of the parent struct fields which hold the child nodes.
+func fieldsOf(parent ast.Node) map[ast.Node]string {
+	v := reflect.ValueOf(parent)
+
+	for v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	names := make(map[ast.Node]string)
+
+	add := func(f reflect.Value, name string) {
+		if !f.CanInterface() {
+			return
+		}
+
+		if child, ok := f.Interface().(ast.Node); ok {
+			if _, exists := names[child]; !exists {
+				names[child] = name
+			}
+		}
+	}
+
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		name := v.Type().Field(i).Name
+
+		switch f.Kind() {
+		case reflect.Ptr, reflect.Interface:
+			if !f.IsNil() {
+				add(f, name)
+			}
+
+		case reflect.Slice:
+			for j := 0; j < f.Len(); j++ {
+				add(f.Index(j), name)
+			}
+		}
+	}
+
+	return names
+}
+
+// typeOf returns the type name of node matched by the node test.
+func (t *tree) typeOf(n ast.Node) string {
+	if t.adapter != nil {
+		return t.adapter.Type(n)
+	}
+
+	return typeName(n)
+}
+
+// pos returns the position of node in the file set.
+func (t *tree) pos(n ast.Node) token.Pos {
+	if t.adapter != nil {
+		return t.adapter.Pos(n)
+	}
+
+	return n.Pos()
+}
+
+func (t *tree) attr(n ast.Node, name string) (Value, bool) {
+	if t.adapter != nil {
+		return t.adapter.Attr(n, name)
+	}
+
+	return NodeAttr(n, name)
+}
+
+// attrNames returns the attribute names of node, or nil if the adapter can't list them.
+func (t *tree) attrNames(n ast.Node) (names []string) {
+	if t.adapter != nil {
+		if lister, ok := t.adapter.(interface{ AttrNames(ast.Node) []string }); ok {
+			return lister.AttrNames(n)
+		}
+
+		return nil
+	}
+
+	for name := range NodeAttrs(n) {
+		names = append(names, name)
+	}
+
+	return
+}
+
+func typeName(n ast.Node) string {
+	if n == nil {
+		return ""
+	}
+
+	t := reflect.TypeOf(n)
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t.Name()
+}
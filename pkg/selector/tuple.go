@@ -0,0 +1,127 @@
+package selector
+
+import (
+	"go/ast"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Tuple holds the nodes matched at the marked steps of a path in step order,
+// or the node matched at the last step if none of the steps is marked.
+// The nodes matched by a set expression are single node tuples.
+type Tuple []ast.Node
+
+func (e *evaluation) evalQueryTuples(q Query, node ast.Node) ([]Tuple, error) {
+	var tuples []Tuple
+
+	for _, expr := range q {
+		var matched []Tuple
+		var err error
+
+		if path, ok := expr.(Path); ok {
+			matched, err = e.evalPathTuples(path, node)
+		} else {
+			var nodes []ast.Node
+
+			nodes, err = e.evalPathExpr(expr, node)
+
+			for _, n := range nodes {
+				matched = append(matched, Tuple{n})
+			}
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		tuples = append(tuples, matched...)
+	}
+
+	return e.sortTuples(tuples), nil
+}
+
+func (e *evaluation) evalPathTuples(path Path, node ast.Node) ([]Tuple, error) {
+	var tuples []Tuple
+
+	marked := path.HasResult()
+
+	var walk func(i int, node ast.Node, prefix Tuple) error
+
+	walk = func(i int, node ast.Node, prefix Tuple) error {
+		step := path[i]
+
+		nodes, err := e.evalStep(step, node)
+
+		if err != nil {
+			return err
+		}
+
+		for _, n := range nodes {
+			tuple := prefix
+
+			if step.Result {
+				tuple = append(prefix[:len(prefix):len(prefix)], n)
+			}
+
+			if i < len(path)-1 {
+				if err := walk(i+1, n, tuple); err != nil {
+					return err
+				}
+			} else if marked {
+				tuples = append(tuples, tuple)
+			} else {
+				tuples = append(tuples, Tuple{n})
+			}
+		}
+
+		return nil
+	}
+
+	if len(path) > 0 {
+		if err := walk(0, node, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return e.sortTuples(tuples), nil
+}
+
+// sortTuples removes the duplicated tuples and sorts the rest in document order.
+func (e *evaluation) sortTuples(tuples []Tuple) []Tuple {
+	seen := make(map[string]bool, len(tuples))
+	sorted := make([]Tuple, 0, len(tuples))
+
+	for _, tuple := range tuples {
+		key := e.tupleKey(tuple)
+
+		if !seen[key] {
+			seen[key] = true
+			sorted = append(sorted, tuple)
+		}
+	}
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		lhs, rhs := sorted[i], sorted[j]
+
+		for k := 0; k < len(lhs) && k < len(rhs); k++ {
+			if l, r := e.tree.order(lhs[k]), e.tree.order(rhs[k]); l != r {
+				return l < r
+			}
+		}
+
+		return len(lhs) < len(rhs)
+	})
+
+	return sorted
+}
+
+func (e *evaluation) tupleKey(tuple Tuple) string {
+	orders := make([]string, len(tuple))
+
+	for i, node := range tuple {
+		orders[i] = strconv.Itoa(e.tree.order(node))
+	}
+
+	return strings.Join(orders, ",")
+}
@@ -0,0 +1,100 @@
+package selector
+
+import (
+	"go/ast"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+const tupleSource = `
+package test
+
+func Foo() {
+	go func() {}()
+}
+
+func Bar() {
+	defer func() {}()
+}
+
+func Baz() {
+	go Foo()
+	go Bar()
+}
+`
+
+func funcNames(nodes []ast.Node) (names []string) {
+	for _, node := range nodes {
+		names = append(names, node.(*ast.FuncDecl).Name.Name)
+	}
+
+	return
+}
+
+func TestResultMarker(t *testing.T) {
+	Convey("Given a parsed file", t, func() {
+		_, f := parseSource(tupleSource)
+
+		Convey("When evaluate a query with result marker in filter", func() {
+			nodes, err := evalQuery(f, "// FuncDecl ! [ .// GoStmt ]")
+
+			So(err, ShouldBeNil)
+			So(funcNames(nodes), ShouldResemble, []string{"Foo", "Baz"})
+		})
+
+		Convey("When evaluate a query with result marker on intermediate step", func() {
+			nodes, err := evalQuery(f, "// FuncDecl ! // GoStmt")
+
+			So(err, ShouldBeNil)
+			So(funcNames(nodes), ShouldResemble, []string{"Foo", "Baz"})
+
+			nodes, err = evalQuery(f, "// FuncDecl ! // DeferStmt, // FuncDecl [ @name == \"Foo\" ]")
+
+			So(err, ShouldBeNil)
+			So(funcNames(nodes), ShouldResemble, []string{"Foo", "Bar"})
+		})
+
+		Convey("When evaluate a query with result marker in sub query", func() {
+			nodes, err := evalQuery(f, "// FuncDecl [ in(..// File // FuncDecl ! // GoStmt) ]")
+
+			So(err, ShouldBeNil)
+			So(funcNames(nodes), ShouldResemble, []string{"Foo", "Baz"})
+		})
+
+		Convey("When evaluate a query with multi result markers", func() {
+			q, err := ParseQuery("// FuncDecl ! // GoStmt ! // Ident")
+
+			So(err, ShouldBeNil)
+
+			tuples, err := q.EvalTuples(f)
+
+			So(err, ShouldBeNil)
+			So(tuples, ShouldHaveLength, 2)
+
+			for i, name := range []string{"Foo", "Bar"} {
+				So(tuples[i], ShouldHaveLength, 2)
+				So(tuples[i][0].(*ast.FuncDecl).Name.Name, ShouldEqual, "Baz")
+				So(tuples[i][1].(*ast.GoStmt).Call.Fun.(*ast.Ident).Name, ShouldEqual, name)
+			}
+
+			nodes, err := q.Eval(f)
+
+			So(err, ShouldBeNil)
+			So(typeNames(nodes), ShouldResemble, []string{"FuncDecl", "GoStmt", "GoStmt"})
+		})
+
+		Convey("When evaluate tuples without result marker", func() {
+			q, err := ParseQuery("// GoStmt // Ident")
+
+			So(err, ShouldBeNil)
+
+			tuples, err := q.EvalTuples(f)
+
+			So(err, ShouldBeNil)
+			So(tuples, ShouldHaveLength, 2)
+			So(tuples[0][0].(*ast.Ident).Name, ShouldEqual, "Foo")
+			So(tuples[1][0].(*ast.Ident).Name, ShouldEqual, "Bar")
+		})
+	})
+}
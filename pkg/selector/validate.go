@@ -0,0 +1,421 @@
+package selector
+
+import (
+	"fmt"
+	"go/ast"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Diagnostic reports the part of query which would never match, e.g. an unknown node type or attribute.
+type Diagnostic struct {
+	Node        fmt.Stringer // the step or expression
+	Message     string
+	Suggestions []string
+}
+
+func (d Diagnostic) String() string {
+	if len(d.Suggestions) == 0 {
+		return d.Message
+	}
+
+	quoted := make([]string, len(d.Suggestions))
+
+	for i, s := range d.Suggestions {
+		quoted[i] = fmt.Sprintf("%q", s)
+	}
+
+	return fmt.Sprintf("%s, did you mean %s?", d.Message, strings.Join(quoted, " or "))
+}
+
+// Validate checks the query against the go/ast schema with the built-in functions.
+//
+//	node type   the match of step is a go/ast node type
+//	axis type   the field of the parent node type holds the matched node type
+//	attribute   the attribute is defined for the node type of step
+//	function    the function is defined and called with the expected arguments
+//
+// The node types of other trees evaluated through EvalTree are unknown to the schema, e.g. `Object` of DocTree,
+// so their queries shouldn't be validated.
+func Validate(q Query) []Diagnostic {
+	return NewEvaluator(nil).Validate(q)
+}
+
+// Validate checks the query against the go/ast schema with the built-in and registered functions.
+func (e *Evaluator) Validate(q Query) []Diagnostic {
+	v := &validator{e: e}
+
+	for _, expr := range q {
+		v.pathExpr(expr)
+	}
+
+	return v.diags
+}
+
+type validator struct {
+	e     *Evaluator
+	diags []Diagnostic
+}
+
+func (v *validator) report(node fmt.Stringer, candidates []string, name string, format string, args ...interface{}) {
+	v.diags = append(v.diags, Diagnostic{node, fmt.Sprintf(format, args...), suggest(name, candidates)})
+}
+
+func (v *validator) pathExpr(expr PathExpr) {
+	switch expr := expr.(type) {
+	case Path:
+		v.path(expr, "")
+
+	case *SetExpr:
+		v.pathExpr(expr.Lhs)
+		v.pathExpr(expr.Rhs)
+	}
+}
+
+// path checks the steps of path from the context node of the type, or any type if empty.
+func (v *validator) path(path Path, context string) {
+	for _, step := range path {
+		context = v.step(step, context)
+	}
+}
+
+// step checks the step from the context node of the type, and returns the type of matched nodes if known.
+func (v *validator) step(step *Step, context string) string {
+	if step.Axis == nil || step.Axis.Dir != "/" {
+		context = ""
+	}
+
+	var match string
+
+	switch {
+	case step.Ref != nil:
+	case step.Not != nil:
+		v.step(step.Not, "")
+	default:
+		match = v.match(step, context)
+	}
+
+	if step.Axis != nil && len(step.Axis.Type) > 0 {
+		v.axisType(step, context, match)
+	}
+
+	if step.Filter != nil {
+		v.expr(step.Filter, match)
+	}
+
+	return match
+}
+
+func (v *validator) match(step *Step, context string) string {
+	match := step.Match
+
+	switch _, known := nodeTypes[match]; {
+	case match == "*":
+		return ""
+
+	case !known:
+		v.report(step, nodeTypeNames, match, "unknown node type %q", match)
+
+		return ""
+
+	case len(context) > 0 && len(step.Axis.Type) == 0 && !hasChild(context, match):
+		v.report(step, nil, "", "%s has no child of type %s", context, match)
+	}
+
+	return match
+}
+
+func (v *validator) axisType(step *Step, context, match string) {
+	field := step.Axis.Type
+
+	if len(context) == 0 {
+		if !allFields[field] {
+			v.report(step, allFieldNames, field, "unknown field %q", field)
+		}
+
+		return
+	}
+
+	fields := nodeFields(context)
+	typ, ok := fields[field]
+
+	if !ok {
+		var names []string
+
+		for name := range fields {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		v.report(step, names, field, "%s has no field %q", context, field)
+
+		return
+	}
+
+	if len(match) > 0 && !holds(typ, match, true) {
+		v.report(step, nil, "", "%s.%s can't hold %s", context, field, match)
+	}
+}
+
+// expr checks the expression which filters the nodes of the type, or any type if empty.
+func (v *validator) expr(expr Expr, context string) {
+	switch expr := expr.(type) {
+	case *Cond:
+		v.expr(expr.Cond, context)
+
+		if expr.Then != nil {
+			v.expr(expr.Then, context)
+		}
+
+		v.expr(expr.Else, context)
+
+	case *Unary:
+		v.expr(expr.Expr, context)
+
+	case *Binary:
+		v.expr(expr.Lhs, context)
+		v.expr(expr.Rhs, context)
+
+	case *FuncCall:
+		v.funcCall(expr)
+
+		for _, arg := range expr.Args {
+			v.expr(arg, context)
+		}
+
+	case *WithAttr:
+		v.attr(expr, context)
+
+	case Path:
+		v.path(expr, context)
+	}
+}
+
+func (v *validator) attr(attr *WithAttr, context string) {
+	if len(context) == 0 {
+		if !allAttrs[attr.ID] {
+			v.report(attr, allAttrNames, attr.ID, "unknown attribute @%s", attr.ID)
+		}
+
+		return
+	}
+
+	attrs := nodeAttrs[context]
+
+	if _, ok := attrs[attr.ID]; !ok {
+		var names []string
+
+		for name := range attrs {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		v.report(attr, names, attr.ID, "%s has no attribute @%s", context, attr.ID)
+	}
+}
+
+func (v *validator) funcCall(call *FuncCall) {
+	def, ok := v.e.lookupFunc(call.ID)
+
+	if !ok {
+		names := make([]string, 0, len(builtins)+len(v.e.funcs))
+
+		for name := range builtins {
+			names = append(names, name)
+		}
+
+		for name := range v.e.funcs {
+			if _, ok := builtins[name]; !ok {
+				names = append(names, name)
+			}
+		}
+
+		sort.Strings(names)
+
+		v.report(call, names, call.ID, "unknown function: %s()", call.ID)
+
+		return
+	}
+
+	if err := def.checkArgs(call.ID, len(call.Args)); err != nil {
+		v.report(call, nil, "", "%s", err)
+	}
+}
+
+// nodeTypes holds the pointer types of the go/ast nodes by type name.
+var nodeTypes = make(map[string]reflect.Type)
+
+var (
+	nodeTypeNames []string
+	allFields     = make(map[string]bool)
+	allFieldNames []string
+	allAttrs      = make(map[string]bool)
+	allAttrNames  []string
+)
+
+func init() {
+	for _, n := range []ast.Node{
+		(*ast.Comment)(nil), (*ast.CommentGroup)(nil), (*ast.Field)(nil), (*ast.FieldList)(nil),
+		(*ast.BadExpr)(nil), (*ast.Ident)(nil), (*ast.Ellipsis)(nil), (*ast.BasicLit)(nil),
+		(*ast.FuncLit)(nil), (*ast.CompositeLit)(nil), (*ast.ParenExpr)(nil), (*ast.SelectorExpr)(nil),
+		(*ast.IndexExpr)(nil), (*ast.IndexListExpr)(nil), (*ast.SliceExpr)(nil), (*ast.TypeAssertExpr)(nil),
+		(*ast.CallExpr)(nil), (*ast.StarExpr)(nil), (*ast.UnaryExpr)(nil), (*ast.BinaryExpr)(nil),
+		(*ast.KeyValueExpr)(nil), (*ast.ArrayType)(nil), (*ast.StructType)(nil), (*ast.FuncType)(nil),
+		(*ast.InterfaceType)(nil), (*ast.MapType)(nil), (*ast.ChanType)(nil), (*ast.BadStmt)(nil),
+		(*ast.DeclStmt)(nil), (*ast.EmptyStmt)(nil), (*ast.LabeledStmt)(nil), (*ast.ExprStmt)(nil),
+		(*ast.SendStmt)(nil), (*ast.IncDecStmt)(nil), (*ast.AssignStmt)(nil), (*ast.GoStmt)(nil),
+		(*ast.DeferStmt)(nil), (*ast.ReturnStmt)(nil), (*ast.BranchStmt)(nil), (*ast.BlockStmt)(nil),
+		(*ast.IfStmt)(nil), (*ast.CaseClause)(nil), (*ast.SwitchStmt)(nil), (*ast.TypeSwitchStmt)(nil),
+		(*ast.CommClause)(nil), (*ast.SelectStmt)(nil), (*ast.ForStmt)(nil), (*ast.RangeStmt)(nil),
+		(*ast.ImportSpec)(nil), (*ast.ValueSpec)(nil), (*ast.TypeSpec)(nil), (*ast.BadDecl)(nil),
+		(*ast.GenDecl)(nil), (*ast.FuncDecl)(nil), (*ast.File)(nil), (*ast.Package)(nil),
+	} {
+		t := reflect.TypeOf(n)
+
+		nodeTypes[t.Elem().Name()] = t
+		nodeTypeNames = append(nodeTypeNames, t.Elem().Name())
+
+		for field := range nodeFields(t.Elem().Name()) {
+			if !allFields[field] {
+				allFields[field] = true
+				allFieldNames = append(allFieldNames, field)
+			}
+		}
+	}
+
+	for _, attrs := range nodeAttrs {
+		for name := range attrs {
+			if !allAttrs[name] {
+				allAttrs[name] = true
+				allAttrNames = append(allAttrNames, name)
+			}
+		}
+	}
+
+	sort.Strings(nodeTypeNames)
+	sort.Strings(allFieldNames)
+	sort.Strings(allAttrNames)
+}
+
+var nodeType = reflect.TypeOf((*ast.Node)(nil)).Elem()
+
+// nodeFields returns the types of the fields which hold the nodes, indexed by field name.
+func nodeFields(name string) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+
+	t, ok := nodeTypes[name]
+
+	if !ok {
+		return fields
+	}
+
+	for i := 0; i < t.Elem().NumField(); i++ {
+		f := t.Elem().Field(i)
+		typ := f.Type
+
+		if typ.Kind() == reflect.Slice {
+			typ = typ.Elem()
+		}
+
+		if f.PkgPath == "" && typ.Implements(nodeType) {
+			fields[f.Name] = typ
+		}
+	}
+
+	return fields
+}
+
+// holds reports whether the field of the type could hold the node of the type name,
+// the fields of a FieldList are also held by the field which holds the list if it is reached by type.
+func holds(field reflect.Type, name string, typed bool) bool {
+	t := nodeTypes[name]
+
+	if field.Kind() == reflect.Interface {
+		return t.Implements(field)
+	}
+
+	return t == field || typed && name == "Field" && field == nodeTypes["FieldList"]
+}
+
+// hasChild reports whether the node of the type could have the child node of the type name.
+func hasChild(parent, name string) bool {
+	for _, field := range nodeFields(parent) {
+		if holds(field, name, false) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// suggest returns the candidates most similar to the name.
+func suggest(name string, candidates []string) (suggestions []string) {
+	if len(name) == 0 {
+		return nil
+	}
+
+	best := len(name)/3 + 1 // the distance of candidates must be less than it
+
+	for _, candidate := range candidates {
+		d := distance(strings.ToLower(name), strings.ToLower(candidate))
+
+		if d < best {
+			best = d
+			suggestions = nil
+		}
+
+		if d == best {
+			suggestions = append(suggestions, candidate)
+		}
+	}
+
+	if len(suggestions) > 3 {
+		suggestions = suggestions[:3]
+	}
+
+	return
+}
+
+// distance returns the edit distance between the strings, which counts the transposition of adjacent characters.
+func distance(s, t string) int {
+	a, b := []rune(s), []rune(t)
+	d := make([][]int, len(a)+1)
+
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			d[i][j] = d[i-1][j-1] + cost
+
+			if d[i-1][j]+1 < d[i][j] {
+				d[i][j] = d[i-1][j] + 1
+			}
+
+			if d[i][j-1]+1 < d[i][j] {
+				d[i][j] = d[i][j-1] + 1
+			}
+
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] && d[i-2][j-2]+1 < d[i][j] {
+				d[i][j] = d[i-2][j-2] + 1
+			}
+		}
+	}
+
+	return d[len(a)][len(b)]
+}
@@ -0,0 +1,101 @@
+package selector
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func diagnostics(diags []Diagnostic) (msgs []string) {
+	for _, diag := range diags {
+		msgs = append(msgs, diag.String())
+	}
+
+	return
+}
+
+func TestValidate(t *testing.T) {
+	Convey("Given a validator", t, func() {
+		Convey("When validate the valid queries", func() {
+			var queries = []string{
+				"// FuncDecl [ @name =~ `^Test` && len(@name) > 4 ]",
+				"// FuncDecl /:Body BlockStmt // ReturnStmt",
+				"// FuncType /:Params Field [ @exported ]",
+				"// FuncDecl / Ident, // * [ @value == 1 ]",
+				"// not(FuncDecl [ @method ]) [ substr(@name, 1) ]",
+				"$funcs [ @exported ] - // GenDecl [ // ValueSpec [ @name ] ]",
+				"// CallExpr //:Fun Ident",
+			}
+
+			for _, s := range queries {
+				q, err := ParseQuery(s)
+
+				So(err, ShouldBeNil)
+				So(Validate(q), ShouldBeEmpty)
+			}
+		})
+
+		Convey("When validate the invalid queries", func() {
+			var queries = map[string][]string{
+				"// FuncDel": {
+					`unknown node type "FuncDel", did you mean "FuncDecl"?`,
+				},
+				"// funcdecl, // Foo": {
+					`unknown node type "funcdecl", did you mean "FuncDecl"?`,
+					`unknown node type "Foo"`,
+				},
+				"// FuncDecl [ @nmae ]": {
+					`FuncDecl has no attribute @nmae, did you mean "name"?`,
+				},
+				"// * [ @nmae ]": {
+					`unknown attribute @nmae, did you mean "name"?`,
+				},
+				"// FuncLit [ @name ]": {
+					"FuncLit has no attribute @name",
+				},
+				"// FuncDecl /:Bdy BlockStmt": {
+					`FuncDecl has no field "Bdy", did you mean "Body"?`,
+				},
+				"// FuncDecl /:Name BlockStmt": {
+					"FuncDecl.Name can't hold BlockStmt",
+				},
+				"// FuncDecl / BasicLit": {
+					"FuncDecl has no child of type BasicLit",
+				},
+				"//:Resluts *": {
+					`unknown field "Resluts", did you mean "Results"?`,
+				},
+				"// FuncDecl [ lenn(@name) ]": {
+					`unknown function: lenn(), did you mean "len"?`,
+				},
+				"// FuncDecl [ len() == substr(@name) ]": {
+					"len() expects 1 argument(s), got 0",
+					"substr() expects 2 to 3 argument(s), got 1",
+				},
+				"// FuncDecl [ / Ident [ @valeu ] ]": {
+					"Ident has no attribute @valeu",
+				},
+			}
+
+			for s, expected := range queries {
+				q, err := ParseQuery(s)
+
+				So(err, ShouldBeNil)
+				So(diagnostics(Validate(q)), ShouldResemble, expected)
+			}
+		})
+
+		Convey("When validate with the registered functions", func() {
+			e := NewEvaluator(nil)
+
+			e.RegisterFunc("isTest", func(ctx *EvalContext, args ...Value) (Value, error) {
+				return Bool(false), nil
+			})
+
+			q, err := ParseQuery("// FuncDecl [ isTest() || isTset() ]")
+
+			So(err, ShouldBeNil)
+			So(diagnostics(e.Validate(q)), ShouldResemble, []string{`unknown function: isTset(), did you mean "isTest"?`})
+		})
+	})
+}
@@ -0,0 +1,313 @@
+package selector
+
+import (
+	"fmt"
+	"go/ast"
+	"strconv"
+	"strings"
+)
+
+// Value is the typed value of expression.
+//
+//	Num      number   64-bit integer
+//	Float    float    64-bit floating point number
+//	Str      string
+//	*Regexp  regexp
+//	Bool     boolean
+//	Null     null     missing attribute or the `null` keyword
+//	NodeSet  nodeset  the nodes matched by a subquery
+//
+// The values are coerced in the expressions,
+//
+//	==, !=      null equals only to null, a boolean operand compares the truth values,
+//	            nodesets are equal if they contain the same nodes, a numeric operand
+//	            compares the numbers, otherwise compares the strings.
+//	<, <=, ...  strings are compared in lexical order, otherwise compares the numbers.
+//	+ - * / % ^ numbers, a float operand promotes the integer one to float,
+//	            `+` concatenates the strings if either operand is string.
+//	& | << >> ~ integers, the integral floats are converted to integer.
+//	& -         intersection and difference if both operands are nodesets.
+//
+// A value is converted to number as: boolean to 1 or 0, null to 0, nodeset to its size,
+// string is parsed as integer or float.
+type Value interface {
+	fmt.Stringer
+
+	TypeName() string
+}
+
+func (n Num) TypeName() string {
+	return "number"
+}
+
+func (f Float) TypeName() string {
+	return "float"
+}
+
+func (s Str) TypeName() string {
+	return "string"
+}
+
+func (r *Regexp) TypeName() string {
+	return "regexp"
+}
+
+type Bool bool
+
+func (b Bool) String() string {
+	return strconv.FormatBool(bool(b))
+}
+
+func (b Bool) TypeName() string {
+	return "boolean"
+}
+
+type Null struct{}
+
+func (n Null) String() string {
+	return "null"
+}
+
+func (n Null) TypeName() string {
+	return "null"
+}
+
+type NodeSet []ast.Node
+
+func (s NodeSet) String() string {
+	var names []string
+
+	for _, node := range s {
+		names = append(names, typeName(node))
+	}
+
+	return "[" + strings.Join(names, ", ") + "]"
+}
+
+func (s NodeSet) TypeName() string {
+	return "nodeset"
+}
+
+func (s NodeSet) Contains(node ast.Node) bool {
+	for _, n := range s {
+		if n == node {
+			return true
+		}
+	}
+
+	return false
+}
+
+// setOp returns the intersection (`&`) or difference (`-`) of the nodes, in the order of lhs.
+func setOp(op string, lhs, rhs []ast.Node) (NodeSet, error) {
+	var nodes NodeSet
+
+	for _, node := range lhs {
+		switch op {
+		case "&":
+			if NodeSet(rhs).Contains(node) {
+				nodes = append(nodes, node)
+			}
+		case "-":
+			if !NodeSet(rhs).Contains(node) {
+				nodes = append(nodes, node)
+			}
+		default:
+			return nil, fmt.Errorf("unexpected set operator: %s", op)
+		}
+	}
+
+	return nodes, nil
+}
+
+func isTrue(v Value) bool {
+	switch v := v.(type) {
+	case Bool:
+		return bool(v)
+	case Num:
+		return v != 0
+	case Float:
+		return v != 0
+	case Str:
+		return len(v) > 0
+	case NodeSet:
+		return len(v) > 0
+	case *Regexp:
+		return v != nil
+	default:
+		return false
+	}
+}
+
+// toNumber converts the value to Num or Float.
+func toNumber(v Value) (Value, error) {
+	switch v := v.(type) {
+	case Num, Float:
+		return v, nil
+	case Bool:
+		if v {
+			return Num(1), nil
+		}
+		return Num(0), nil
+	case Null:
+		return Num(0), nil
+	case Str:
+		s := strings.TrimSpace(string(v))
+
+		if n, err := strconv.ParseInt(s, 0, 64); err == nil {
+			return Num(n), nil
+		}
+
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return Float(f), nil
+		}
+
+		return nil, fmt.Errorf("cannot convert %s to number", v)
+	case NodeSet:
+		return Num(len(v)), nil
+	default:
+		return nil, fmt.Errorf("cannot convert %s to number", v.TypeName())
+	}
+}
+
+// toNum converts the value to integer.
+func toNum(v Value) (Num, error) {
+	n, err := toNumber(v)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if f, ok := n.(Float); ok {
+		if f != Float(Num(f)) {
+			return 0, fmt.Errorf("cannot convert %s to integer", f)
+		}
+
+		return Num(f), nil
+	}
+
+	return n.(Num), nil
+}
+
+func toFloat(v Value) Float {
+	if n, ok := v.(Num); ok {
+		return Float(n)
+	}
+
+	return v.(Float)
+}
+
+func toStr(v Value) string {
+	switch v := v.(type) {
+	case Str:
+		return string(v)
+	case *Regexp:
+		return v.Regexp.String()
+	default:
+		return v.String()
+	}
+}
+
+func equals(lhs, rhs Value) bool {
+	switch {
+	case isNull(lhs) || isNull(rhs):
+		return isNull(lhs) && isNull(rhs)
+
+	case isBool(lhs) || isBool(rhs):
+		return isTrue(lhs) == isTrue(rhs)
+
+	case isNodeSet(lhs) && isNodeSet(rhs):
+		l, r := lhs.(NodeSet), rhs.(NodeSet)
+
+		if len(l) != len(r) {
+			return false
+		}
+
+		for _, node := range l {
+			if !r.Contains(node) {
+				return false
+			}
+		}
+
+		return true
+
+	case isNumber(lhs) || isNumber(rhs):
+		n, err := compare(lhs, rhs)
+
+		return err == nil && n == 0
+
+	default:
+		return toStr(lhs) == toStr(rhs)
+	}
+}
+
+func compare(lhs, rhs Value) (int, error) {
+	if isStr(lhs) && isStr(rhs) {
+		return strings.Compare(string(lhs.(Str)), string(rhs.(Str))), nil
+	}
+
+	l, err := toNumber(lhs)
+	if err != nil {
+		return 0, err
+	}
+
+	r, err := toNumber(rhs)
+	if err != nil {
+		return 0, err
+	}
+
+	if l, ok := l.(Num); ok {
+		if r, ok := r.(Num); ok {
+			switch {
+			case l < r:
+				return -1, nil
+			case l > r:
+				return 1, nil
+			default:
+				return 0, nil
+			}
+		}
+	}
+
+	switch lf, rf := toFloat(l), toFloat(r); {
+	case lf < rf:
+		return -1, nil
+	case lf > rf:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+func isNull(v Value) bool {
+	_, ok := v.(Null)
+
+	return ok || v == nil
+}
+
+func isBool(v Value) bool {
+	_, ok := v.(Bool)
+
+	return ok
+}
+
+func isNumber(v Value) bool {
+	switch v.(type) {
+	case Num, Float:
+		return true
+	default:
+		return false
+	}
+}
+
+func isStr(v Value) bool {
+	_, ok := v.(Str)
+
+	return ok
+}
+
+func isNodeSet(v Value) bool {
+	_, ok := v.(NodeSet)
+
+	return ok
+}
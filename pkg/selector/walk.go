@@ -0,0 +1,94 @@
+package selector
+
+// walkQuery calls the function for each expression in the query,
+// including the expressions of the sub queries and the referenced queries.
+func walkQuery(q Query, fn func(expr Expr)) {
+	(&walker{onExpr: fn, enterRefs: true}).query(q)
+}
+
+// walkRefs calls the function for each reference in the query, including the references in the filters
+// and function arguments, without entering the referenced queries.
+func walkRefs(q Query, fn func(ref *Ref)) {
+	(&walker{onRef: fn}).query(q)
+}
+
+type walker struct {
+	onExpr    func(expr Expr)
+	onRef     func(ref *Ref)
+	enterRefs bool
+}
+
+func (w *walker) query(q Query) {
+	for _, path := range q {
+		w.pathExpr(path)
+	}
+}
+
+func (w *walker) pathExpr(expr PathExpr) {
+	switch expr := expr.(type) {
+	case Path:
+		w.path(expr)
+
+	case *SetExpr:
+		w.pathExpr(expr.Lhs)
+		w.pathExpr(expr.Rhs)
+	}
+}
+
+func (w *walker) path(path Path) {
+	for _, step := range path {
+		w.step(step)
+	}
+}
+
+func (w *walker) step(step *Step) {
+	if step.Not != nil {
+		w.step(step.Not)
+	}
+
+	if step.Ref != nil {
+		if w.onRef != nil {
+			w.onRef(step.Ref)
+		}
+
+		if w.enterRefs {
+			w.query(step.Ref.Query)
+		}
+	}
+
+	if step.Filter != nil {
+		w.expr(step.Filter)
+	}
+}
+
+func (w *walker) expr(expr Expr) {
+	if w.onExpr != nil {
+		w.onExpr(expr)
+	}
+
+	switch expr := expr.(type) {
+	case *Cond:
+		w.expr(expr.Cond)
+
+		if expr.Then != nil {
+			w.expr(expr.Then)
+		}
+
+		w.expr(expr.Else)
+
+	case *Unary:
+		w.expr(expr.Expr)
+
+	case *Binary:
+		w.expr(expr.Lhs)
+		w.expr(expr.Rhs)
+
+	case *FuncCall:
+		for _, arg := range expr.Args {
+			w.expr(arg)
+		}
+
+	case Path:
+		w.path(expr)
+	}
+}